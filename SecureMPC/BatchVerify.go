@@ -0,0 +1,164 @@
+package SecureMPC
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+)
+
+// "BatchVerify" checks the proofs of many signature shares at once. The proof of share j from player i for the
+// message with x~ = x^(4 Delta) holds when
+//
+//	c_j = H(transcript of v, x~, v_i, x_j^2, v'_j, x'_j),  v^z_j = v'_j * v_i^c_j  and  x~^z_j = x'_j * x_j^(2 c_j)
+//
+// Instead of checking the equations one by one, they are raised to random 128 bit exponents r_j and r'_j and
+// multiplied together, which leaves
+//
+//	v^(sum r_j z_j) * prod_m x~_m^(sum_{j for m} r'_j z_j) = prod_j v'_j^r_j x'_j^r'_j x_j^(2 r'_j c_j) * prod_i v_i^(sum_{j of i} r_j c_j)
+//
+// This only needs one full exponentiation of v and one for each message, instead of four for each share. A wrong
+// share passes with probability about 2^-128, except that the equations are only checked up to a square root
+// of 1. This does not matter, as the signature shares are squared when they are combined.
+// If the batch fails, the shares are verified one by one with VerifyShare to find the invalid ones.
+
+// batchExponentBits is the length of the random exponents of the batch
+const batchExponentBits = 128
+
+// BatchItem is a signature share to be batch verified, together with the message it is for
+type BatchItem struct {
+	Msg    string
+	Digest []byte   // Digest is the hash of the message for shares created by SignDigest, otherwise nil and Msg is hashed
+	X      *big.Int // X is the encoding of Msg given to SignEncodedMsg, or nil if the player encoded Msg itself
+	Share  *SignatureShare
+}
+
+// BatchVerifyShares verifies all signature shares of the batch, which may be for different messages.
+// It returns the indices of the invalid items, which is empty if all shares are valid.
+func BatchVerifyShares(items []BatchItem, params *PublicParams) []int {
+	if batchVerify(items, params) {
+		return []int{}
+	}
+	invalid := []int{}
+	for i, item := range items {
+		if !verifyBatchItem(item, params) {
+			invalid = append(invalid, i)
+		}
+	}
+	return invalid
+}
+
+// BatchVerifySharesOfMsg verifies the signature shares of a single message, as gathered by a player.
+// It returns the ids of the players whose shares are invalid.
+func BatchVerifySharesOfMsg(msg string, sigShares map[int]*SignatureShare, params *PublicParams) []int {
+	ids := make([]int, 0, len(sigShares))
+	items := make([]BatchItem, 0, len(sigShares))
+	for id, share := range sigShares {
+		ids = append(ids, id)
+		items = append(items, BatchItem{Msg: msg, Share: share})
+	}
+	invalid := []int{}
+	for _, i := range BatchVerifyShares(items, params) {
+		invalid = append(invalid, ids[i])
+	}
+	return invalid
+}
+
+func verifyBatchItem(item BatchItem, params *PublicParams) bool {
+	digest, err := item.digest(params)
+	if err != nil {
+		return false
+	}
+	if item.X != nil {
+		return VerifyEncodedDigestShare(digest, item.X, item.Share, params)
+	}
+	return VerifyDigestShare(digest, item.Share, params)
+}
+
+// digest returns the hash of the message of the item
+func (item BatchItem) digest(params *PublicParams) ([]byte, error) {
+	if item.Digest != nil {
+		return item.Digest, nil
+	}
+	return params.HashMessage([]byte(item.Msg))
+}
+
+// batchVerify returns true if all shares of the batch are valid
+func batchVerify(items []BatchItem, params *PublicParams) bool {
+	if params.Proof.Check() != nil {
+		return false
+	}
+	n := params.N
+	keyID := params.KeyID()
+	twodelta := new(big.Int).Mul(Two, params.Delta)
+	fourdelta := new(big.Int).Mul(Two, twodelta)
+	exponentBound := new(big.Int).Lsh(One, batchExponentBits)
+	// xtildes and xtildeExponents are indexed by the digest of the message and its encoding
+	xtildes := map[string]*big.Int{}
+	xtildeExponents := map[string]*big.Int{}
+	vExponent := big.NewInt(0)
+	// verificationKeyExponents[i] is the sum of r_j c_j for the shares of player i
+	verificationKeyExponents := map[int]*big.Int{}
+	rhs := big.NewInt(1)
+	for _, item := range items {
+		share := item.Share
+		if share == nil || share.vprime == nil {
+			return false // Shares without commitments can only be verified one by one
+		}
+		digest, err := item.digest(params)
+		if err != nil {
+			return false
+		}
+		x := item.X
+		if x == nil {
+			if x, err = params.encodeDigest(digest); err != nil {
+				return false
+			}
+		} else if params.checkEncoding(digest, x) != nil {
+			return false
+		}
+		if !bytes.Equal(share.digest, digest) || !bytes.Equal(share.keyID, keyID) {
+			return false
+		}
+		if share.id < 1 || share.id > params.L || !params.Proof.zInRange(share.z) {
+			return false
+		}
+		messageKey := string(digest) + x.String()
+		xtilde, known := xtildes[messageKey]
+		if !known {
+			xtilde = new(big.Int).Exp(x, fourdelta, n)
+			xtildes[messageKey] = xtilde
+			xtildeExponents[messageKey] = big.NewInt(0)
+		}
+		xisquared := new(big.Int).Exp(share.signature, Two, n)
+		c := params.proofChallenge(signatureProofDomain, keyID, digest, share.id, xtilde, xisquared, share.vprime, share.xprime)
+		if c.Cmp(share.c) != 0 {
+			return false
+		}
+		r, err := rand.Int(rand.Reader, exponentBound)
+		if err != nil {
+			return false
+		}
+		rprime, err := rand.Int(rand.Reader, exponentBound)
+		if err != nil {
+			return false
+		}
+		vExponent.Add(vExponent, new(big.Int).Mul(r, share.z))
+		xtildeExponents[messageKey].Add(xtildeExponents[messageKey], new(big.Int).Mul(rprime, share.z))
+		if verificationKeyExponents[share.id] == nil {
+			verificationKeyExponents[share.id] = big.NewInt(0)
+		}
+		verificationKeyExponents[share.id].Add(verificationKeyExponents[share.id], new(big.Int).Mul(r, share.c))
+		rhs.Mul(rhs, new(big.Int).Exp(share.vprime, r, n)).Mod(rhs, n)
+		rhs.Mul(rhs, new(big.Int).Exp(share.xprime, rprime, n)).Mod(rhs, n)
+		xiExponent := new(big.Int).Mul(rprime, share.c)
+		rhs.Mul(rhs, new(big.Int).Exp(share.signature, xiExponent.Lsh(xiExponent, 1), n)).Mod(rhs, n)
+	}
+	for id, exponent := range verificationKeyExponents {
+		rhs.Mul(rhs, new(big.Int).Exp(params.VerificationKeys[id], exponent, n)).Mod(rhs, n)
+	}
+	lhs := new(big.Int).Exp(params.V, vExponent, n)
+	for messageKey, xtilde := range xtildes {
+		lhs.Mul(lhs, new(big.Int).Exp(xtilde, xtildeExponents[messageKey], n)).Mod(lhs, n)
+	}
+	return lhs.Cmp(rhs) == 0
+}
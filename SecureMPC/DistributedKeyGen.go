@@ -0,0 +1,620 @@
+package SecureMPC
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+)
+
+// "DistributedKeyGen" creates the same threshold RSA setup as ThresholdProtocolSetup, but without a trusted dealer.
+// It follows Boneh and Franklin, "Efficient Generation of Shared RSA Keys":
+//
+//	1. Every player i picks p_i and q_i, such that p = p_1 + ... + p_l and q = q_1 + ... + q_l
+//	2. N = p*q is computed with the BGW protocol, so only N is revealed
+//	3. N is tested for small factors and then with the distributed biprimality test
+//	4. Every player derives an additive share d_i of d = e^-1 mod phi(N) from its share of phi(N)
+//	5. The additive shares are turned into Shamir shares over the integers, s_j = f(j) where f(0) = Delta * d
+//
+// Every player runs its own KeyGenPlayer, which only learns the messages the other players send it, so the shares
+// of p, q and d never leave the player. The players go through the rounds together: each round takes the messages
+// all players sent in the round before and returns the messages of this player for the next one. If N turns out
+// not to be usable, all players find so from the same public values and start over at step 1 together. The public
+// random values, as the bases of the biprimality test, are hashed from N, so no player chooses them.
+//
+// Since nobody knows the order of the group, all sharing of d is done over the integers. The Lagrange
+// coefficients used in CreateSignature are integers, so signing and combining work unchanged, with the extra
+// Delta in the Scale of the public parameters. Over the integers f(j) = f(0) mod j, so sharing d itself would
+// give away d mod j to player j; Delta * d is a multiple of every j <= l.
+// Note that N is not a product of safe primes, as Boneh and Franklin cannot guarantee that.
+
+// statisticalSecurity is the amount of bits by which random values over the integers are larger than the values
+// they blind
+const statisticalSecurity = 128
+
+// biprimalityRounds is the amount of rounds of the biprimality test. Each round lets a false N pass with
+// probability at most 1/2
+const biprimalityRounds = 40
+
+// sieveBound is the bound of the small primes used to test N for small factors before the biprimality test
+const sieveBound = 10000
+
+// minDistributedKeySize is the smallest length of N in bits the distributed key generation accepts
+const minDistributedKeySize = 512
+
+// The rounds of the distributed key generation, named after the messages sent in the round before
+const (
+	keyGenRoundFactorShares     = iota + 1 // private BGW shares of p_i, q_i and of zero modulo the field prime
+	keyGenRoundModulusShares               // broadcast shares of N
+	keyGenRoundBiprimality                 // broadcast values of the biprimality test, private BGW shares modulo N
+	keyGenRoundGcdShares                   // broadcast shares of r(p + q - 1) mod N
+	keyGenRoundPhi                         // broadcast phi_i mod e
+	keyGenRoundTrial                       // broadcast x^d_i of the trial exponentiation
+	keyGenRoundKeyShares                   // private integer shares of Delta * d_i, broadcast squares making up V
+	keyGenRoundVerificationKeys            // broadcast verification keys
+	keyGenDone
+)
+
+// KeyGenMessage is a message of a player in the distributed key generation. Broadcast messages must reach every
+// player; the others must only be sent to player To, over a private and authenticated channel.
+type KeyGenMessage struct {
+	Round   int        // Round is the round the message is for
+	Attempt int        // Attempt counts the candidates for N, which start over at round 1
+	From    int        // From is the id of the sending player
+	To      int        // To is the id of the receiving player, 0 for a broadcast
+	Values  []*big.Int // Values are the numbers sent, which depend on the round
+}
+
+// KeyGenPlayer is a player of the distributed key generation. The shares of p, q, phi(N) and d never leave it.
+type KeyGenPlayer struct {
+	Id         int
+	l          int
+	k          int
+	t          int      // t = k-1 is the degree of the BGW polynomials, any t players learn nothing
+	keysize    int      // keysize is the length of N in bits
+	fieldPrime *big.Int // fieldPrime is the public prime larger than N used for the BGW computation of N
+	random     io.Reader
+
+	round          int // round is the round of the messages the player expects next
+	attempt        int
+	n              *big.Int
+	pShare         *big.Int      // pShare is the additive share of p
+	qShare         *big.Int      // qShare is the additive share of q
+	phiShare       *big.Int      // phiShare is the additive share of phi(N)
+	dShare         *big.Int      // dShare is the additive share of d
+	secretKeyShare *big.Int      // secretKeyShare is the final Shamir share of Delta * d
+	v              *big.Int      // v is the product of the squares published by the players
+	params         *PublicParams // params are set once the key generation is done
+}
+
+// NewKeyGenPlayer creates player id of a distributed key generation between l players with threshold k, for a
+// modulus of keysize bits. random is the source of randomness of this player, nil means crypto/rand.Reader.
+//
+// The BGW computation of N runs with polynomials of degree t = k-1, so it is private against the same k-1
+// players as the key shares are. Multiplying two such polynomials needs the shares of 2t+1 players, thus
+// l >= 2k-1 is required, and k >= 2, since for t = 0 every player would learn the shares of p and q of all
+// others. keysize must be even and at least minDistributedKeySize. Otherwise ErrInvalidParams is returned.
+func NewKeyGenPlayer(random io.Reader, id, l, k, keysize int) (*KeyGenPlayer, error) {
+	if err := checkThreshold(l, k); err != nil {
+		return nil, err
+	}
+	if k < 2 || l < 2*k-1 {
+		return nil, fmt.Errorf("%w: the distributed key generation needs k >= 2 and l >= 2k-1, not l = %d and k = %d", ErrInvalidParams, l, k)
+	}
+	if keysize < minDistributedKeySize || keysize%2 != 0 {
+		return nil, fmt.Errorf("%w: keysize must be even and at least %d bits, not %d", ErrInvalidParams, minDistributedKeySize, keysize)
+	}
+	if err := checkPublicExponent(l, e); err != nil {
+		return nil, err
+	}
+	if id < 1 || id > l {
+		return nil, fmt.Errorf("%w: player id must be between 1 and %d, not %d", ErrInvalidParams, l, id)
+	}
+	// The smallest prime above 2^keysize, which every player finds by itself
+	fieldPrime := new(big.Int).Lsh(One, uint(keysize))
+	fieldPrime.Add(fieldPrime, One)
+	for !fieldPrime.ProbablyPrime(20) {
+		fieldPrime.Add(fieldPrime, Two)
+	}
+	return &KeyGenPlayer{
+		Id:         id,
+		l:          l,
+		k:          k,
+		t:          k - 1,
+		keysize:    keysize,
+		fieldPrime: fieldPrime,
+		random:     randomOrDefault(random),
+	}, nil
+}
+
+// Start returns the messages of the player for round 1
+func (p *KeyGenPlayer) Start() ([]*KeyGenMessage, error) {
+	if p.round != 0 {
+		return nil, errors.New("key generation has already started")
+	}
+	return p.nextAttempt()
+}
+
+// Step takes the messages all players sent this player in the current round, including its own, and returns the
+// messages of the player for the next round. It returns no messages once the key generation is done. If any of
+// the received messages is invalid, the error names the players that sent them.
+func (p *KeyGenPlayer) Step(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	switch p.round {
+	case keyGenRoundFactorShares:
+		return p.receiveFactorShares(received)
+	case keyGenRoundModulusShares:
+		return p.receiveModulusShares(received)
+	case keyGenRoundBiprimality:
+		return p.receiveBiprimality(received)
+	case keyGenRoundGcdShares:
+		return p.receiveGcdShares(received)
+	case keyGenRoundPhi:
+		return p.receivePhi(received)
+	case keyGenRoundTrial:
+		return p.receiveTrial(received)
+	case keyGenRoundKeyShares:
+		return p.receiveKeyShares(received)
+	case keyGenRoundVerificationKeys:
+		return nil, p.receiveVerificationKeys(received)
+	case keyGenDone:
+		return nil, errors.New("key generation is done")
+	}
+	return nil, errors.New("key generation has not started")
+}
+
+// Done tells if the key generation is done, such that Result returns the key
+func (p *KeyGenPlayer) Done() bool {
+	return p.round == keyGenDone
+}
+
+// Result returns the public parameters and the key share of this player once the key generation is done
+func (p *KeyGenPlayer) Result() (*PublicParams, *KeyShare, error) {
+	if !p.Done() {
+		return nil, nil, errors.New("key generation is not done")
+	}
+	return p.params, &KeyShare{Id: p.Id, secret: new(big.Int).Set(p.secretKeyShare)}, nil
+}
+
+// nextAttempt picks new shares of p and q, and shares them for computing N = pq with the BGW protocol
+func (p *KeyGenPlayer) nextAttempt() ([]*KeyGenMessage, error) {
+	p.attempt++
+	p.round = keyGenRoundFactorShares
+	p.n = nil
+	half := p.keysize / 2
+	// p_1 lies in [2^(h-1) + 2^(h-2), 2^(h-1) + 2^(h-2) + 2^(h-3)) and the sum of the others below 2^(h-3),
+	// which gives p exactly h bits, and N exactly keysize bits
+	offset := new(big.Int).Add(new(big.Int).Lsh(One, uint(half-1)), new(big.Int).Lsh(One, uint(half-2)))
+	firstBound := new(big.Int).Lsh(One, uint(half-3))
+	otherBound := new(big.Int).Lsh(One, uint(half-3-big.NewInt(int64(p.l)).BitLen()))
+	var err error
+	if p.pShare, err = p.randomPrimeShare(offset, firstBound, otherBound); err != nil {
+		return nil, err
+	}
+	if p.qShare, err = p.randomPrimeShare(offset, firstBound, otherBound); err != nil {
+		return nil, err
+	}
+	return p.multiplicationShares(p.pShare, p.qShare, p.fieldPrime)
+}
+
+// randomPrimeShare picks the share of a prime, such that the prime is 3 mod 4.
+// Player 1 picks a share that is 3 mod 4 and the others a share that is 0 mod 4.
+func (p *KeyGenPlayer) randomPrimeShare(offset, firstBound, otherBound *big.Int) (*big.Int, error) {
+	if p.Id == 1 {
+		share, err := rand.Int(p.random, firstBound)
+		if err != nil {
+			return nil, randomnessError(err)
+		}
+		share.Add(share, offset)
+		share.SetBit(share, 0, 1)
+		return share.SetBit(share, 1, 1), nil
+	}
+	share, err := rand.Int(p.random, otherBound)
+	if err != nil {
+		return nil, randomnessError(err)
+	}
+	share.SetBit(share, 0, 0)
+	return share.SetBit(share, 1, 0), nil
+}
+
+// receiveFactorShares computes the share of N from the BGW shares of p and q, and publishes it
+func (p *KeyGenPlayer) receiveFactorShares(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	_, private, err := p.collect(received, 0, 3)
+	if err != nil {
+		return nil, err
+	}
+	p.round = keyGenRoundModulusShares
+	return []*KeyGenMessage{p.message(0, multiplicationShare(private, p.fieldPrime))}, nil
+}
+
+// receiveModulusShares interpolates N, tests it for small factors and starts the biprimality test
+func (p *KeyGenPlayer) receiveModulusShares(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	broadcast, _, err := p.collect(received, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	n := p.interpolate(broadcast, p.fieldPrime)
+	if new(big.Int).GCD(nil, nil, n, smallPrimesProduct).Cmp(One) != 0 || n.Bit(0) != 1 || n.Bit(1) != 0 {
+		return p.nextAttempt() // N must have no small factors and be 1 mod 4
+	}
+	p.n = n
+	// Player 1 publishes v_1 = g^((N - p_1 - q_1 + 1)/4) and the others v_i = g^((p_i + q_i)/4)
+	exponent := new(big.Int).Add(p.pShare, p.qShare)
+	if p.Id == 1 {
+		exponent.Sub(n, exponent).Add(exponent, One)
+	}
+	exponent.Div(exponent, big.NewInt(4))
+	values := make([]*big.Int, biprimalityRounds)
+	for round := range values {
+		values[round] = new(big.Int).Exp(p.biprimalityBase(round), exponent, n)
+	}
+	// gcd(N, p + q - 1) = 1 rules out N = p^a * q^b, which is computed as gcd(N, r(p + q - 1) mod N) for a
+	// shared random r
+	r, err := rand.Int(p.random, n)
+	if err != nil {
+		return nil, randomnessError(err)
+	}
+	sum := new(big.Int).Add(p.pShare, p.qShare)
+	if p.Id == 1 {
+		sum.Sub(sum, One)
+	}
+	p.round = keyGenRoundBiprimality
+	messages, err := p.multiplicationShares(r, sum, n)
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, p.message(0, values...)), nil
+}
+
+// receiveBiprimality checks that v_1 = +-v_2 * ... * v_l for every base of the biprimality test, and publishes
+// the share of r(p + q - 1) mod N
+func (p *KeyGenPlayer) receiveBiprimality(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	broadcast, private, err := p.collect(received, biprimalityRounds, 3)
+	if err != nil {
+		return nil, err
+	}
+	for round := 0; round < biprimalityRounds; round++ {
+		product := big.NewInt(1)
+		for i := 2; i <= p.l; i++ {
+			product.Mul(product, broadcast[i][round]).Mod(product, p.n)
+		}
+		v1 := broadcast[1][round]
+		if v1.Cmp(product) != 0 && v1.Cmp(new(big.Int).Sub(p.n, product)) != 0 {
+			return p.nextAttempt()
+		}
+	}
+	p.round = keyGenRoundGcdShares
+	return []*KeyGenMessage{p.message(0, multiplicationShare(private, p.n))}, nil
+}
+
+// receiveGcdShares finishes the biprimality test, and publishes phi_i mod e. Player 1 has
+// phi_1 = N - p_1 - q_1 + 1 and the others phi_i = -(p_i + q_i).
+func (p *KeyGenPlayer) receiveGcdShares(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	broadcast, _, err := p.collect(received, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	z := p.interpolate(broadcast, p.n)
+	if new(big.Int).GCD(nil, nil, z, p.n).Cmp(One) != 0 {
+		return p.nextAttempt()
+	}
+	p.phiShare = new(big.Int).Neg(new(big.Int).Add(p.pShare, p.qShare))
+	if p.Id == 1 {
+		p.phiShare.Add(p.phiShare, p.n).Add(p.phiShare, One)
+	}
+	p.round = keyGenRoundPhi
+	return []*KeyGenMessage{p.message(0, new(big.Int).Mod(p.phiShare, e))}, nil
+}
+
+// receivePhi computes the additive share d_i of d, such that e*d = 1 mod phi(N), and publishes x^d_i for the
+// trial exponentiation
+func (p *KeyGenPlayer) receivePhi(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	broadcast, _, err := p.collect(received, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	psi := big.NewInt(0)
+	for i := 1; i <= p.l; i++ {
+		psi.Add(psi, broadcast[i][0])
+	}
+	psi.Mod(psi, e)
+	if psi.Sign() == 0 {
+		return p.nextAttempt() // e divides phi(N), so the modulus can not be used
+	}
+	// zeta = -psi^-1 mod e, so that 1 + zeta*phi is divisible by e and d = (1 + zeta*phi)/e
+	zeta := new(big.Int).ModInverse(psi, e)
+	zeta.Sub(e, zeta)
+	numerator := new(big.Int).Mul(zeta, p.phiShare)
+	if p.Id == 1 {
+		numerator.Add(numerator, One)
+	}
+	p.dShare = numerator.Div(numerator, e) // Div rounds down, so the sum of d_i is missing between 0 and l-1
+	p.round = keyGenRoundTrial
+	return []*KeyGenMessage{p.message(0, new(big.Int).Exp(p.trialBase(), p.dShare, p.n))}, nil
+}
+
+// receiveTrial finds the amount missing from the sum of the d_i by the trial exponentiation, which player 1 adds
+// to d_1, and deals the Shamir shares of Delta * d_i. Every player also publishes a square, such that V is the
+// product of the squares and nobody knows a square root of it.
+func (p *KeyGenPlayer) receiveTrial(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	broadcast, _, err := p.collect(received, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	x := p.trialBase()
+	y := big.NewInt(1)
+	for i := 1; i <= p.l; i++ {
+		y.Mul(y, broadcast[i][0]).Mod(y, p.n)
+	}
+	missing := 0
+	for ; missing < p.l && new(big.Int).Exp(y, e, p.n).Cmp(x) != 0; missing++ {
+		y.Mul(y, x).Mod(y, p.n)
+	}
+	if missing == p.l {
+		return p.nextAttempt()
+	}
+	if p.Id == 1 {
+		p.dShare.Add(p.dShare, big.NewInt(int64(missing)))
+	}
+	shares, err := shareInteger(p.random, p.dShare, p.n, p.l, p.k)
+	if err != nil {
+		return nil, err
+	}
+	// The additive shares are not needed any more
+	p.pShare, p.qShare, p.phiShare, p.dShare = nil, nil, nil, nil
+	root, err := rand.Int(p.random, p.n)
+	if err != nil {
+		return nil, randomnessError(err)
+	}
+	p.round = keyGenRoundKeyShares
+	messages := make([]*KeyGenMessage, 0, p.l+1)
+	for j := 1; j <= p.l; j++ {
+		messages = append(messages, p.message(j, shares[j]))
+	}
+	return append(messages, p.message(0, root.Mul(root, root).Mod(root, p.n))), nil
+}
+
+// receiveKeyShares adds up the shares of the Delta * d_i into the key share s_j, and publishes the verification
+// key V^s_j
+func (p *KeyGenPlayer) receiveKeyShares(received []*KeyGenMessage) ([]*KeyGenMessage, error) {
+	broadcast, private, err := p.collect(received, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	p.secretKeyShare = big.NewInt(0)
+	p.v = big.NewInt(1)
+	for i := 1; i <= p.l; i++ {
+		p.secretKeyShare.Add(p.secretKeyShare, private[i][0])
+		p.v.Mul(p.v, broadcast[i][0]).Mod(p.v, p.n)
+	}
+	p.round = keyGenRoundVerificationKeys
+	return []*KeyGenMessage{p.message(0, new(big.Int).Exp(p.v, p.secretKeyShare, p.n))}, nil
+}
+
+// receiveVerificationKeys puts together the public parameters
+func (p *KeyGenPlayer) receiveVerificationKeys(received []*KeyGenMessage) error {
+	broadcast, _, err := p.collect(received, 1, 0)
+	if err != nil {
+		return err
+	}
+	verificationKeys := make([]*big.Int, p.l+1)
+	for i := 1; i <= p.l; i++ {
+		verificationKeys[i] = broadcast[i][0]
+	}
+	params := newPublicParams(p.l, p.k, p.n, e, p.v, verificationKeys)
+	params.Scale = new(big.Int).Set(params.Delta)
+	params.Proof.ShareBits = integerShareBound(p.n, p.l, p.k, p.l).BitLen()
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	p.params = params
+	p.round = keyGenDone
+	return nil
+}
+
+// message creates a message of this player for the round it expects next. to = 0 is a broadcast.
+func (p *KeyGenPlayer) message(to int, values ...*big.Int) *KeyGenMessage {
+	return &KeyGenMessage{Round: p.round, Attempt: p.attempt, From: p.Id, To: to, Values: values}
+}
+
+// collect checks that the received messages are one broadcast message with broadcastValues values and one private
+// message to this player with privateValues values from every player, for the current round and attempt, where 0
+// values means no such message. It returns the values of the messages by sender.
+func (p *KeyGenPlayer) collect(received []*KeyGenMessage, broadcastValues, privateValues int) (map[int][]*big.Int, map[int][]*big.Int, error) {
+	broadcast := map[int][]*big.Int{}
+	private := map[int][]*big.Int{}
+	invalid := map[int]bool{}
+	for _, m := range received {
+		if m == nil || m.From < 1 || m.From > p.l {
+			return nil, nil, fmt.Errorf("%w: message from an unknown player", ErrInvalidKeyGen)
+		}
+		values, count := broadcast, broadcastValues
+		if m.To != 0 {
+			values, count = private, privateValues
+		}
+		if m.Round != p.round || m.Attempt != p.attempt || (m.To != 0 && m.To != p.Id) || count == 0 ||
+			len(m.Values) != count || values[m.From] != nil || !validValues(m.Values) {
+			invalid[m.From] = true
+			continue
+		}
+		values[m.From] = m.Values
+	}
+	for i := 1; i <= p.l; i++ {
+		if (broadcastValues > 0 && broadcast[i] == nil) || (privateValues > 0 && private[i] == nil) {
+			invalid[i] = true
+		}
+	}
+	if len(invalid) > 0 {
+		ids := make([]int, 0, len(invalid))
+		for id := range invalid {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		return nil, nil, &PlayerError{Err: ErrInvalidKeyGen, PlayerIds: ids}
+	}
+	return broadcast, private, nil
+}
+
+// validValues tells if none of the values is nil. Shares over the integers may be negative.
+func validValues(values []*big.Int) bool {
+	for _, value := range values {
+		if value == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// multiplicationShares shares a and b with random polynomials of degree t modulo base, and zero with one of degree
+// 2t used to rerandomize the product, for computing (a_1 + ... + a_l) * (b_1 + ... + b_l) mod base with the BGW
+// protocol. Each player gets its three shares in a private message.
+func (p *KeyGenPlayer) multiplicationShares(a, b, base *big.Int) ([]*KeyGenMessage, error) {
+	aPoly, err := GenerateRandomBigPolynomial(p.random, a, base, p.t)
+	if err != nil {
+		return nil, err
+	}
+	bPoly, err := GenerateRandomBigPolynomial(p.random, b, base, p.t)
+	if err != nil {
+		return nil, err
+	}
+	zeroPoly, err := GenerateRandomBigPolynomial(p.random, big.NewInt(0), base, 2*p.t)
+	if err != nil {
+		return nil, err
+	}
+	aShares := GenerateSecretShares(aPoly, base, p.l)
+	bShares := GenerateSecretShares(bPoly, base, p.l)
+	zeroShares := GenerateSecretShares(zeroPoly, base, p.l)
+	messages := make([]*KeyGenMessage, 0, p.l)
+	for j := 1; j <= p.l; j++ {
+		messages = append(messages, p.message(j, aShares[j], bShares[j], zeroShares[j]))
+	}
+	return messages, nil
+}
+
+// multiplicationShare adds up the BGW shares received from every player and multiplies them, which gives this
+// player's share of the product on a polynomial of degree 2t
+func multiplicationShare(shares map[int][]*big.Int, base *big.Int) *big.Int {
+	a := big.NewInt(0)
+	b := big.NewInt(0)
+	z := big.NewInt(0)
+	for _, values := range shares {
+		a.Add(a, values[0])
+		b.Add(b, values[1])
+		z.Add(z, values[2])
+	}
+	product := a.Mul(a, b)
+	return product.Add(product, z).Mod(product, base)
+}
+
+// interpolate computes the product from the shares of the first 2t+1 players
+func (p *KeyGenPlayer) interpolate(shares map[int][]*big.Int, base *big.Int) *big.Int {
+	first := map[int]*big.Int{}
+	for i := 1; i <= 2*p.t+1; i++ {
+		first[i] = shares[i][0]
+	}
+	return interpolateAtZero(first, base)
+}
+
+// interpolateAtZero computes f(0) mod base from the shares f(i) mod base
+func interpolateAtZero(shares map[int]*big.Int, base *big.Int) *big.Int {
+	sum := big.NewInt(0)
+	for i, share := range shares {
+		// top/bottom = (0-j)/(i-j)
+		top := big.NewInt(1)
+		bottom := big.NewInt(1)
+		for j := range shares {
+			if j != i {
+				top.Mul(top, big.NewInt(int64(-j)))
+				bottom.Mul(bottom, big.NewInt(int64(i-j)))
+			}
+		}
+		bottom.Mod(bottom, base).ModInverse(bottom, base)
+		lambda := top.Mul(top, bottom)
+		sum.Add(sum, lambda.Mul(lambda, share))
+	}
+	return sum.Mod(sum, base)
+}
+
+// publicTranscript starts the transcript the public random values are hashed from, which depend on N only
+func (p *KeyGenPlayer) publicTranscript() *Transcript {
+	t := NewTranscript(crypto.SHA256, "SecureMPC distributed key generation")
+	t.AppendBigInt("n", p.n)
+	return t
+}
+
+// biprimalityBase is the public base g of the given round of the biprimality test, with Jacobi symbol 1
+func (p *KeyGenPlayer) biprimalityBase(round int) *big.Int {
+	t := p.publicTranscript()
+	t.AppendInt("round", round)
+	for {
+		g := t.Challenge("base", p.n.BitLen()+statisticalSecurity)
+		if g.Mod(g, p.n); big.Jacobi(g, p.n) == 1 {
+			return g
+		}
+	}
+}
+
+// trialBase is the public x of the trial exponentiation
+func (p *KeyGenPlayer) trialBase() *big.Int {
+	x := p.publicTranscript().Challenge("trial", p.n.BitLen()+statisticalSecurity)
+	return x.Mod(x, p.n)
+}
+
+// shareInteger shares Delta * secret between l players with a random integer polynomial f of degree k-1, and
+// returns shares[i] = f(i), shares[0] is unused. The secret must be below N in absolute value. Since f(i) = f(0)
+// mod i and Delta is a multiple of i, the share of player i says nothing about the secret modulo i, which it
+// would if f(0) were the secret itself.
+func shareInteger(random io.Reader, secret, n *big.Int, l, k int) ([]*big.Int, error) {
+	delta := new(big.Int).MulRange(1, int64(l))
+	poly, err := GenerateRandomBigPolynomial(random, delta.Mul(delta, secret), integerCoefficientBound(n, l), k-1)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*big.Int, l+1)
+	for i := 1; i <= l; i++ {
+		shares[i] = poly.evalInteger(big.NewInt(int64(i)))
+	}
+	return shares, nil
+}
+
+// integerCoefficientBound is the bound of the coefficients of an integer sharing between l players of Delta times
+// secrets below N. They are larger than Delta * N by a statistical slack.
+func integerCoefficientBound(n *big.Int, l int) *big.Int {
+	delta := new(big.Int).MulRange(1, int64(l))
+	return new(big.Int).Lsh(new(big.Int).Mul(n, delta), statisticalSecurity)
+}
+
+// integerShareBound is a public bound on the key shares, when each of the dealers shares a secret below N with
+// shareInteger. Each s_j is the sum of polynomials with constant term below Delta * N and k-1 coefficients below
+// the coefficient bound, evaluated at j <= l.
+func integerShareBound(n *big.Int, l, k, dealers int) *big.Int {
+	lpowk := new(big.Int).Exp(big.NewInt(int64(l)), big.NewInt(int64(k-1)), nil)
+	bound := new(big.Int).Mul(integerCoefficientBound(n, l), lpowk)
+	bound.Mul(bound, big.NewInt(int64(k-1)))
+	bound.Add(bound, new(big.Int).Mul(n, new(big.Int).MulRange(1, int64(l))))
+	return bound.Mul(bound, big.NewInt(int64(dealers)))
+}
+
+// evalInteger evaluates the polynomial over the integers, without reducing modulo anything
+func (p *BigPolynomial) evalInteger(x *big.Int) *big.Int {
+	result := new(big.Int).Set(p.constant)
+	xpowi := big.NewInt(1)
+	for i := 0; i < len(p.coefs); i++ {
+		xpowi = new(big.Int).Mul(xpowi, x)
+		result.Add(result, new(big.Int).Mul(xpowi, p.coefs[i]))
+	}
+	return result
+}
+
+// smallPrimesProduct is the product of all odd primes below sieveBound
+var smallPrimesProduct = func() *big.Int {
+	product := big.NewInt(1)
+	for i := int64(3); i < sieveBound; i += 2 {
+		if big.NewInt(i).ProbablyPrime(0) {
+			product.Mul(product, big.NewInt(i))
+		}
+	}
+	return product
+}()
@@ -0,0 +1,268 @@
+package SecureMPC
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// "Encoding" is about how the hash of a message is turned into the number x in Z_N which is signed.
+// With EncodingRawHash the digest itself is signed, as in Shoup's paper. With EncodingPKCS1v15 the digest is
+// padded as in EMSA-PKCS1-v1_5 (RFC 8017), so a combined signature is a standard RSA signature under (N, E),
+// which can be checked by rsa.VerifyPKCS1v15 or OpenSSL.
+//
+// EncodingPSS is randomized, so the players can not encode the message themselves. Instead a coordinator picks
+// the salt and computes the EMSA-PSS encoding x once with EncodePSS. Every player checks x with SignEncodedMsg
+// before signing it, and the combined signature is accepted by rsa.VerifyPSS. The salt is as long as the hash.
+// PSS and its mask generation function MGF1 use the message hash of the public parameters.
+//
+// With EncodingFDH the digest is expanded with MGF1 to a full domain hash in Z_N*, as in RSA-FDH. The encoding is
+// deterministic and RSA signatures are unique, so the signature of a message is a verifiable random function of
+// it, see FDHOutput.
+
+// SignatureEncoding selects how messages are encoded before signing
+type SignatureEncoding int
+
+const (
+	EncodingRawHash  SignatureEncoding = iota // EncodingRawHash signs the digest as an integer
+	EncodingPKCS1v15                          // EncodingPKCS1v15 signs the EMSA-PKCS1-v1_5 encoded digest
+	EncodingPSS                               // EncodingPSS signs the EMSA-PSS encoded digest chosen by a coordinator
+	EncodingFDH                               // EncodingFDH signs the digest expanded to a full domain hash in Z_N*
+)
+
+// Domains of the full domain hash and of the output derived from an FDH signature
+const (
+	fdhDomain       = "SecureMPC RSA-FDH v1"
+	fdhOutputDomain = "SecureMPC RSA-FDH output v1"
+)
+
+func (encoding SignatureEncoding) String() string {
+	switch encoding {
+	case EncodingRawHash:
+		return "raw"
+	case EncodingPKCS1v15:
+		return "pkcs1v15"
+	case EncodingPSS:
+		return "pss"
+	case EncodingFDH:
+		return "fdh"
+	}
+	return fmt.Sprintf("SignatureEncoding(%d)", int(encoding))
+}
+
+// ParseSignatureEncoding returns the encoding with the given name, as returned by String
+func ParseSignatureEncoding(name string) (SignatureEncoding, error) {
+	for _, encoding := range []SignatureEncoding{EncodingRawHash, EncodingPKCS1v15, EncodingPSS, EncodingFDH} {
+		if encoding.String() == name {
+			return encoding, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signature encoding %q", name)
+}
+
+// encodeDigest computes the number x which is signed for the given digest
+func (params *PublicParams) encodeDigest(digest []byte) (*big.Int, error) {
+	if err := params.checkDigest(digest); err != nil {
+		return nil, err
+	}
+	switch params.Encoding {
+	case EncodingRawHash:
+		return new(big.Int).SetBytes(digest), nil
+	case EncodingPKCS1v15:
+		return encodePKCS1v15(params.Hash, digest, params.modulusSize())
+	case EncodingPSS:
+		return nil, errors.New("PSS encoding is randomized, a coordinator must encode the message with EncodePSS")
+	case EncodingFDH:
+		return encodeFDH(params.Hash, digest, params.N), nil
+	}
+	return nil, fmt.Errorf("unknown signature encoding %d", params.Encoding)
+}
+
+// checkEncoding checks that x is an encoding of the digest
+func (params *PublicParams) checkEncoding(digest []byte, x *big.Int) error {
+	if params.Encoding == EncodingPSS {
+		if err := params.checkDigest(digest); err != nil {
+			return err
+		}
+		return verifyPSS(params.Hash, digest, x, params.N.BitLen()-1)
+	}
+	expected, err := params.encodeDigest(digest)
+	if err != nil {
+		return err
+	}
+	if expected.Cmp(x) != 0 {
+		return errors.New("x is not the encoding of the message")
+	}
+	return nil
+}
+
+// EncodePSS is used by the coordinator to compute the EMSA-PSS encoding of msg with a salt read from random, which
+// all players then sign with SignEncodedMsg. nil means crypto/rand.Reader.
+func EncodePSS(random io.Reader, msg string, params *PublicParams) (*big.Int, error) {
+	digest, err := params.HashMessage([]byte(msg))
+	if err != nil {
+		return nil, err
+	}
+	return EncodePSSDigest(random, digest, params)
+}
+
+// EncodePSSDigest is EncodePSS for a message that is already hashed with the message hash
+func EncodePSSDigest(random io.Reader, digest []byte, params *PublicParams) (*big.Int, error) {
+	if params.Encoding != EncodingPSS {
+		return nil, errors.New("public parameters do not use PSS encoding")
+	}
+	if err := params.checkDigest(digest); err != nil {
+		return nil, err
+	}
+	salt := make([]byte, params.Hash.Size())
+	if _, err := io.ReadFull(randomOrDefault(random), salt); err != nil {
+		return nil, randomnessError(err)
+	}
+	return encodePSS(params.Hash, digest, salt, params.N.BitLen()-1)
+}
+
+// encodePSS is EMSA-PSS-ENCODE of RFC 8017 with MGF1, both using hash
+func encodePSS(hash crypto.Hash, digest, salt []byte, emBits int) (*big.Int, error) {
+	hLen := len(digest)
+	emLen := (emBits + 7) / 8
+	if emLen < hLen+len(salt)+2 {
+		return nil, errors.New("modulus is too short for PSS encoding")
+	}
+	h := pssHash(hash, digest, salt)
+	// DB = PS || 0x01 || salt, where PS are zero bytes
+	db := make([]byte, emLen-hLen-1)
+	db[len(db)-len(salt)-1] = 0x01
+	copy(db[len(db)-len(salt):], salt)
+	mask := mgf1(hash, h, len(db))
+	for i := range db {
+		db[i] ^= mask[i]
+	}
+	db[0] &= 0xff >> (8*emLen - emBits)
+	em := append(append(db, h...), 0xbc)
+	return new(big.Int).SetBytes(em), nil
+}
+
+// verifyPSS is EMSA-PSS-VERIFY of RFC 8017 with MGF1, both using hash, and a salt as long as the hash
+func verifyPSS(hash crypto.Hash, digest []byte, x *big.Int, emBits int) error {
+	hLen := len(digest)
+	sLen := hLen
+	emLen := (emBits + 7) / 8
+	if emLen < hLen+sLen+2 || x.BitLen() > emBits {
+		return errors.New("x is not a PSS encoding")
+	}
+	em := x.FillBytes(make([]byte, emLen))
+	if em[emLen-1] != 0xbc {
+		return errors.New("x is not a PSS encoding")
+	}
+	db := em[:emLen-hLen-1]
+	h := em[emLen-hLen-1 : emLen-1]
+	mask := mgf1(hash, h, len(db))
+	for i := range db {
+		db[i] ^= mask[i]
+	}
+	db[0] &= 0xff >> (8*emLen - emBits)
+	psLength := emLen - hLen - sLen - 2
+	for _, b := range db[:psLength] {
+		if b != 0 {
+			return errors.New("x is not a PSS encoding")
+		}
+	}
+	if db[psLength] != 0x01 {
+		return errors.New("x is not a PSS encoding")
+	}
+	if !bytes.Equal(h, pssHash(hash, digest, db[len(db)-sLen:])) {
+		return errors.New("x is not the PSS encoding of the message")
+	}
+	return nil
+}
+
+// pssHash computes H = Hash(0x00 * 8 || digest || salt)
+func pssHash(hash crypto.Hash, digest, salt []byte) []byte {
+	h := hash.New()
+	h.Write(make([]byte, 8))
+	h.Write(digest)
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+// mgf1 is the mask generation function MGF1 of RFC 8017 with hash
+func mgf1(hash crypto.Hash, seed []byte, length int) []byte {
+	mask := make([]byte, 0, length+hash.Size())
+	counter := make([]byte, 4)
+	for i := uint32(0); len(mask) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := hash.New()
+		h.Write(seed)
+		h.Write(counter)
+		mask = h.Sum(mask)
+	}
+	return mask[:length]
+}
+
+// encodeFDH expands the digest to a number in Z_N*. The candidates MGF1(domain || counter || digest) are cut to the
+// bit length of N and the first one in Z_N* is taken. A candidate is at least N with probability below 1/2, as
+// N has its top bit set, so fewer than 2 candidates are needed on average.
+func encodeFDH(hash crypto.Hash, digest []byte, n *big.Int) *big.Int {
+	size := (n.BitLen() + 7) / 8
+	x := new(big.Int)
+	for counter := uint32(0); ; counter++ {
+		seed := append([]byte(fdhDomain), binary.BigEndian.AppendUint32(nil, counter)...)
+		candidate := mgf1(hash, append(seed, digest...), size)
+		candidate[0] &= 0xff >> (8*size - n.BitLen())
+		x.SetBytes(candidate)
+		if x.Sign() > 0 && x.Cmp(n) < 0 && new(big.Int).GCD(nil, nil, x, n).Cmp(One) == 0 {
+			return x
+		}
+	}
+}
+
+// FDHOutput derives a pseudorandom output from the FDH signature y of a message. Since the signature is unique,
+// anyone can check the output with VerifySignature, while nobody can predict it without k signature shares.
+func FDHOutput(y *big.Int, params *PublicParams) ([]byte, error) {
+	if params.Encoding != EncodingFDH {
+		return nil, errors.New("public parameters do not use FDH encoding")
+	}
+	if err := checkMessageHash(params.Hash); err != nil {
+		return nil, err
+	}
+	if y.Sign() <= 0 || y.Cmp(params.N) >= 0 {
+		return nil, errors.New("signature is not in range [1,N-1]")
+	}
+	h := params.Hash.New()
+	h.Write([]byte(fdhOutputDomain))
+	h.Write(SignatureBytes(y, params))
+	return h.Sum(nil), nil
+}
+
+// encodePKCS1v15 computes EM = 0x00 || 0x01 || PS || 0x00 || DigestInfo, where PS are 0xff bytes filling EM up to
+// size bytes
+func encodePKCS1v15(hash crypto.Hash, digest []byte, size int) (*big.Int, error) {
+	prefix := digestInfoPrefixes[hash]
+	digestInfoLength := len(prefix) + len(digest)
+	if size < digestInfoLength+11 {
+		return nil, errors.New("modulus is too short for PKCS #1 v1.5 encoding")
+	}
+	em := make([]byte, size)
+	em[1] = 0x01
+	for i := 2; i < size-digestInfoLength-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[size-digestInfoLength:], prefix)
+	copy(em[size-len(digest):], digest)
+	return new(big.Int).SetBytes(em), nil
+}
+
+// modulusSize is the length of N in bytes
+func (params *PublicParams) modulusSize() int {
+	return (params.N.BitLen() + 7) / 8
+}
+
+// SignatureBytes encodes the signature y as big endian bytes of the same length as N, which is the format used
+// by crypto/rsa and other RSA implementations
+func SignatureBytes(y *big.Int, params *PublicParams) []byte {
+	return y.FillBytes(make([]byte, params.modulusSize()))
+}
@@ -0,0 +1,52 @@
+package SecureMPC
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the threshold API. They can be wrapped in a PlayerError, so use errors.Is to check for them.
+var (
+	ErrInvalidShare        = errors.New("invalid signature share")
+	ErrInsufficientShares  = errors.New("too few signature shares")
+	ErrInvalidSignature    = errors.New("signature shares do not combine to a valid signature")
+	ErrRandomness          = errors.New("reading randomness failed")
+	ErrEncoding            = errors.New("message can not be encoded")
+	ErrInvalidCiphertext   = errors.New("invalid ciphertext")
+	ErrDecryption          = errors.New("decryption error")
+	ErrStaleKeyShare       = errors.New("key share belongs to another epoch of the key")
+	ErrInvalidRefresh      = errors.New("invalid refresh share")
+	ErrInvalidReshare      = errors.New("invalid reshare share")
+	ErrInvalidRecovery     = errors.New("invalid recovery share")
+	ErrSafePrimePoolEmpty  = errors.New("safe prime pool is empty")
+	ErrPooledPrimesRefused = errors.New("pooled safe primes are refused")
+	ErrUnsuitableKey       = errors.New("RSA key is not suitable for threshold signatures")
+	ErrInvalidParams       = errors.New("invalid public parameters")
+	ErrSelfTest            = errors.New("self test of the threshold key failed")
+	ErrWrongPurpose        = errors.New("key is dealt for another purpose")
+	ErrInvalidKeyGen       = errors.New("invalid key generation message")
+)
+
+// PlayerError is an error caused by, or concerning, the players with the given ids
+type PlayerError struct {
+	Err       error
+	PlayerIds []int
+}
+
+func (e *PlayerError) Error() string {
+	return fmt.Sprintf("%v (players %v)", e.Err, e.PlayerIds)
+}
+
+func (e *PlayerError) Unwrap() error {
+	return e.Err
+}
+
+// randomnessError wraps an error from reading randomness, so it can be told apart with ErrRandomness
+func randomnessError(err error) error {
+	return fmt.Errorf("%w: %w", ErrRandomness, err)
+}
+
+// encodingError wraps an error from encoding a message, so it can be told apart with ErrEncoding
+func encodingError(err error) error {
+	return fmt.Errorf("%w: %w", ErrEncoding, err)
+}
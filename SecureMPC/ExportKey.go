@@ -0,0 +1,135 @@
+package SecureMPC
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// "ExportKey" hands the public key (N, E) of a threshold key to systems that know nothing about threshold
+// signatures: as a crypto/rsa public key, in PKIX PEM armor, as an OpenSSH authorized key and as a JSON Web Key.
+// A combined signature is an ordinary RSA signature, so these systems can verify it if they agree on the
+// encoding, which is PKCS #1 v1.5 or PSS for all of them.
+//
+// The key id of the JSON Web Key is its RFC 7638 thumbprint, which only depends on N and E. Unlike KeyID it does
+// not change when the key shares are refreshed or reshared, as the public key stays the same.
+
+// PKIXPEMType is the type of the PEM blocks of PKIX public keys
+const PKIXPEMType = "PUBLIC KEY"
+
+// RSAPublicKey returns the public key of the threshold key for crypto/rsa
+func (params *PublicParams) RSAPublicKey() (*rsa.PublicKey, error) {
+	if params.N == nil || params.E == nil || !params.E.IsInt64() || params.E.Int64() < 3 || params.E.Int64() > math.MaxInt32 {
+		return nil, fmt.Errorf("public exponent %v is not supported by crypto/rsa", params.E)
+	}
+	return &rsa.PublicKey{N: new(big.Int).Set(params.N), E: int(params.E.Int64())}, nil
+}
+
+// EncodePublicKeyPEM encodes the public key of the threshold key as a PKIX PEM block
+func EncodePublicKeyPEM(params *PublicParams) ([]byte, error) {
+	key, err := params.RSAPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PKIXPEMType, Bytes: der}), nil
+}
+
+// EncodeAuthorizedKey encodes the public key of the threshold key as a line of an OpenSSH authorized_keys file.
+// The comment is appended if it is not empty.
+func EncodeAuthorizedKey(params *PublicParams, comment string) ([]byte, error) {
+	if _, err := params.RSAPublicKey(); err != nil {
+		return nil, err
+	}
+	// RFC 4253 section 6.6: string "ssh-rsa", mpint e, mpint n
+	var blob bytes.Buffer
+	writeSSHString(&blob, []byte("ssh-rsa"))
+	writeSSHString(&blob, sshMPInt(params.E))
+	writeSSHString(&blob, sshMPInt(params.N))
+	line := "ssh-rsa " + base64.StdEncoding.EncodeToString(blob.Bytes())
+	if comment != "" {
+		line += " " + comment
+	}
+	return []byte(line + "\n"), nil
+}
+
+// jwk is a JSON Web Key of an RSA public key as of RFC 7517 and RFC 7518
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// EncodeJWK encodes the public key of the threshold key as a JSON Web Key with JWKThumbprint as key id. The
+// algorithm is given if the encoding and hash of the parameters have a JWA name. Decryption keys are marked
+// for encryption and have no signature algorithm.
+func EncodeJWK(params *PublicParams) ([]byte, error) {
+	kid, err := JWKThumbprint(params)
+	if err != nil {
+		return nil, err
+	}
+	use, alg := "sig", jwaAlgorithm(params)
+	if params.Purpose == PurposeDecryption {
+		use, alg = "enc", ""
+	}
+	return json.Marshal(jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: use,
+		Alg: alg,
+		N:   base64.RawURLEncoding.EncodeToString(params.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(params.E.Bytes()),
+	})
+}
+
+// JWKThumbprint returns the RFC 7638 thumbprint of the public key of the threshold key, the base64url encoded
+// SHA-256 hash of its required JSON Web Key members in lexicographic order
+func JWKThumbprint(params *PublicParams) (string, error) {
+	if _, err := params.RSAPublicKey(); err != nil {
+		return "", err
+	}
+	canonical := `{"e":"` + base64.RawURLEncoding.EncodeToString(params.E.Bytes()) +
+		`","kty":"RSA","n":"` + base64.RawURLEncoding.EncodeToString(params.N.Bytes()) + `"}`
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// jwaAlgorithm returns the RFC 7518 name of the signature algorithm of the parameters, or "" if there is none
+func jwaAlgorithm(params *PublicParams) string {
+	prefix := map[SignatureEncoding]string{EncodingPKCS1v15: "RS", EncodingPSS: "PS"}[params.Encoding]
+	size := map[crypto.Hash]string{crypto.SHA256: "256", crypto.SHA384: "384", crypto.SHA512: "512"}[params.Hash]
+	if prefix == "" || size == "" {
+		return ""
+	}
+	return prefix + size
+}
+
+// writeSSHString writes b with a 4 byte big endian length, as an SSH string
+func writeSSHString(buf *bytes.Buffer, b []byte) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(b)))
+	buf.Write(b)
+}
+
+// sshMPInt encodes the non-negative x as an SSH mpint, which has a leading zero byte if the top bit is set
+func sshMPInt(x *big.Int) []byte {
+	b := x.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
@@ -2,10 +2,12 @@ package SecureMPC
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	_ "github.com/davecgh/go-spew/spew"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	str "strings"
@@ -30,18 +32,47 @@ func Play() {
 	}
 
 	// Validate
-	// TODO: validate input
+	if err := checkThreshold(l, k); err != nil {
+		fmt.Println("Invalid input:", err)
+		return
+	}
 
 	// Setup
 	fmt.Println("Creating data. Please wait ...")
-	data := ThresholdProtocolSetup(l, k, 1024)
+	pool := openGamePrimePool(gameKeysize)
+	data, err := ThresholdProtocolSetup(l, k, gameKeysize)
+	if err != nil {
+		fmt.Println("Setup failed:", err)
+		return
+	}
 	fmt.Println("Data created")
+	if pool != nil {
+		// Refill the pool for the next run while waiting for commands
+		pool.FillInBackground(context.Background(), 2, &SafePrimeOptions{Workers: 1})
+	}
 	fmt.Println("Type 'help' for an overview of commands")
 
 	// Start listening
 	messenger(data)
 }
 
+// gameKeysize is the size of the modulus of the game
+const gameKeysize = 1024
+
+// openGamePrimePool draws the safe primes of the game from a pool in the cache directory of the user, such that
+// the setup does not have to wait for fresh ones. It returns nil if there is no such pool.
+func openGamePrimePool(keysize int) *SafePrimePool {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	pool, err := OpenSafePrimePool(filepath.Join(dir, "SecureMPC", "safeprimes"), keysize/2)
+	if err != nil || UseSafePrimePool(pool) != nil {
+		return nil
+	}
+	return pool
+}
+
 // defaults to player one
 var currentPlayer = 1
 
@@ -152,7 +183,10 @@ var commands = map[string]*Command{
 		action: func(args []string, data *ThresholdProtocolData) bool {
 			message := args[0]
 			// Player will sign message and save the signature share in its own object
-			data.Participants[currentPlayer].SignHashOfMsg(message)
+			if _, err := data.Participants[currentPlayer].SignHashOfMsg(message); err != nil {
+				fmt.Println("Signing failed:", err)
+				return true
+			}
 			fmt.Print("Signature share created\n")
 			return true
 		}},
@@ -167,12 +201,15 @@ var commands = map[string]*Command{
 			}
 			message := args[1]
 			// Check if this message has a signature share
-			share, msgHasSig := data.Participants[currentPlayer].KnownSignatures[message][currentPlayer]
+			share, msgHasSig := data.Participants[currentPlayer].KnownShares(message)[currentPlayer]
 			if !msgHasSig {
 				fmt.Printf("You need to sign this message first. Refer to 'help'\n")
 				return true
 			}
-			SendSignatureShare(message, share, receivingPlayerId, data)
+			if err := SendSignatureShare(message, share, receivingPlayerId, data); err != nil {
+				fmt.Println("Sending failed:", err)
+				return true
+			}
 			fmt.Printf("Signature share was sent to Player#%d\n", receivingPlayerId)
 			return true
 		}},
@@ -186,13 +223,13 @@ var commands = map[string]*Command{
 		args:        []string{},
 		description: "View signed and signable messages",
 		action: func(args []string, data *ThresholdProtocolData) bool {
-			messages := data.Participants[currentPlayer].KnownSignatures
+			messages := data.Participants[currentPlayer].KnownMessages()
 			if len(messages) == 0 {
 				fmt.Println("No signed or unsigned messages. Try signing your own!")
 				return true
 			}
-			for msg, playerSignatures := range messages {
-				if _, contained := playerSignatures[currentPlayer]; contained {
+			for _, msg := range messages {
+				if _, contained := data.Participants[currentPlayer].KnownShares(msg)[currentPlayer]; contained {
 					fmt.Print("[  signed] ")
 				} else {
 					fmt.Print("[unsigned] ")
@@ -207,8 +244,8 @@ var commands = map[string]*Command{
 		action: func(args []string, data *ThresholdProtocolData) bool {
 			message := args[0]
 			// Check if this player has signature shares for this message
-			shares, msgHasShares := data.Participants[currentPlayer].KnownSignatures[message]
-			if !msgHasShares {
+			shares := data.Participants[currentPlayer].KnownShares(message)
+			if len(shares) == 0 {
 				fmt.Printf("No shares. Nobody has sent you shares and you have not signed this message yourself. \n")
 				return true
 			}
@@ -225,12 +262,13 @@ var commands = map[string]*Command{
 		description: "Try computing the full signature of specified message using known signature shares",
 		action: func(args []string, data *ThresholdProtocolData) bool {
 			message := args[0]
-			sigmap := data.Participants[currentPlayer-1].KnownSignatures[message]
-			sig, valid := CreateSignature(message, data, sigmap)
-			if !valid {
-				fmt.Println("Error")
+			sigmap := data.Participants[currentPlayer].KnownShares(message)
+			sig, err := CreateSignature(message, data.PublicParams, sigmap)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return true
 			}
-			if VerifySignature(message, sig, data) {
+			if VerifySignature(message, sig, data.PublicParams) {
 				fmt.Println("Success!")
 			} else {
 				fmt.Println("Failure!")
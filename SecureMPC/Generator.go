@@ -1,7 +1,9 @@
 package SecureMPC
 
 import (
+	"context"
 	"crypto/rand"
+	"io"
 	"math/big"
 	"strconv"
 )
@@ -15,26 +17,46 @@ var e = big.NewInt(65537)
 // GeneratePrimes will generate two modulus such that for primes p, q, p', q',
 // we have n=p*q and m=p'*q' and p=2p'+1 q=2q' + 1
 // security is the length of the RSA Modulus
-func GeneratePrimes(security int) (*big.Int, *big.Int) {
-	var helper func() (*big.Int, *big.Int)
-	certainty := 128
-	helper = func() (*big.Int, *big.Int) {
-		qprime, _ := rand.Prime(rand.Reader, (security/2)-1)
-		q := new(big.Int).Add(new(big.Int).Mul(qprime, Two), One)
-		if q.ProbablyPrime(certainty) {
-			return q, qprime
+// random is the source of randomness, nil means crypto/rand.Reader
+func GeneratePrimes(random io.Reader, security int) (*big.Int, *big.Int, error) {
+	return GeneratePrimesContext(context.Background(), random, security, nil)
+}
+
+// GeneratePrimesContext is GeneratePrimes, which stops with ctx.Err() once ctx is done and searches the safe
+// primes as set by opts, see GenerateSafePrime. If random is nil, the safe primes are taken from a pool while it
+// has any, see UseSafePrimePool.
+func GeneratePrimesContext(ctx context.Context, random io.Reader, security int, opts *SafePrimeOptions) (*big.Int, *big.Int, error) {
+	p, pprime, err := pooledOrNewSafePrime(ctx, random, security/2, opts, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		q, qprime, err := pooledOrNewSafePrime(ctx, random, security/2, opts, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if q.Cmp(p) != 0 {
+			return new(big.Int).Mul(p, q), new(big.Int).Mul(pprime, qprime), nil
 		}
-		return helper()
 	}
-	p, pprime := helper()
-	q, qprime := helper()
-	return new(big.Int).Mul(p, q), new(big.Int).Mul(pprime, qprime)
 }
 
-// GenerateRandomQuadratic will create a number v^2, where 0<v<n is uniformly random
-func GenerateRandomQuadratic(n *big.Int) *big.Int {
-	v, _ := rand.Int(rand.Reader, n)
-	return new(big.Int).Mul(v, v)
+// pooledOrNewSafePrime takes a safe prime from a pool, or generates one if there is none
+func pooledOrNewSafePrime(ctx context.Context, random io.Reader, bits int, opts *SafePrimeOptions, found int) (*big.Int, *big.Int, error) {
+	p, pprime, err := takePooledPrime(random, bits, opts)
+	if err != nil || p != nil {
+		return p, pprime, err
+	}
+	return generateSafePrime(ctx, random, bits, opts, found, 2)
+}
+
+// GenerateRandomQuadratic will create a number v^2 mod n, where 0<v<n is uniformly random
+func GenerateRandomQuadratic(random io.Reader, n *big.Int) (*big.Int, error) {
+	v, err := rand.Int(randomOrDefault(random), n)
+	if err != nil {
+		return nil, randomnessError(err)
+	}
+	return new(big.Int).Exp(v, Two, n), nil
 }
 
 // Generates the parts of the RSA key, where PubKey=(n,e) and SecKey=(n,d)
@@ -42,11 +64,19 @@ func GenerateRandomQuadratic(n *big.Int) *big.Int {
 // e is the public exponent
 // d is the private exponent
 // m is size of the quadratic subgroup
-func GenerateRSAKey(security int) (*big.Int, *big.Int, *big.Int, *big.Int) {
-	n, m := GeneratePrimes(security)
+func GenerateRSAKey(random io.Reader, security int) (*big.Int, *big.Int, *big.Int, *big.Int, error) {
+	return GenerateRSAKeyContext(context.Background(), random, security, nil)
+}
+
+// GenerateRSAKeyContext is GenerateRSAKey with the cancellation and options of GeneratePrimesContext
+func GenerateRSAKeyContext(ctx context.Context, random io.Reader, security int, opts *SafePrimeOptions) (*big.Int, *big.Int, *big.Int, *big.Int, error) {
+	n, m, err := GeneratePrimesContext(ctx, random, security, opts)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
 	// This mod inverse should not be able to fail, as m should be a product of two primes, none of which can be equal to e
 	d := new(big.Int).ModInverse(e, m)
-	return n, e, d, m
+	return n, e, d, m, nil
 }
 
 type BigPolynomial struct {
@@ -81,15 +111,18 @@ func (p *BigPolynomial) String() string {
 // eval_zero is the secret to be shared,
 // fbase is the modulo base and
 // s_i the shares to be distributed
-func GenerateRandomBigPolynomial(eval_zero *big.Int, base *big.Int, degree int) *BigPolynomial {
+func GenerateRandomBigPolynomial(random io.Reader, eval_zero *big.Int, base *big.Int, degree int) (*BigPolynomial, error) {
 	coefs := make([]*big.Int, degree)
 	for i := 0; i < degree; i++ {
-		coefs[i], _ = rand.Int(rand.Reader, base)
+		var err error
+		if coefs[i], err = rand.Int(randomOrDefault(random), base); err != nil {
+			return nil, randomnessError(err)
+		}
 	}
 	return &BigPolynomial{
 		constant: eval_zero,
 		coefs:    coefs,
-	}
+	}, nil
 }
 
 // GenerateSecretShares will use a polynomial to generate secret key shares for l participants
@@ -0,0 +1,99 @@
+package SecureMPC
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// "ImportKey" converts an existing RSA signing key to threshold custody: the private exponent is split into key
+// shares as Deal does, and the public key stays the same, so signatures made by the players verify as before.
+//
+// Not every RSA key can be split. Shoup's scheme needs N = pq for safe primes p = 2p'+1 and q = 2q'+1, such that
+// the squares modulo N form a cyclic group of order m = p'q' without small factors, and a prime public exponent
+// e > l, such that e is coprime to the Delta of the Lagrange combination. Keys made by crypto/rsa.GenerateKey have
+// no safe primes, so only keys made for threshold use in the first place can be imported, as by GenerateRSAKey.
+
+// PKCS8PEMType is the type of the PEM blocks of PKCS #8 private keys
+const PKCS8PEMType = "PRIVATE KEY"
+
+// DealFromRSAKey will split the private key into key shares for l players, such that k of them are needed to
+// sign, as Deal does. The key is checked to be suitable first, or else an ErrUnsuitableKey is returned.
+// random is the source of randomness, nil means crypto/rand.Reader.
+func DealFromRSAKey(random io.Reader, l, k int, key *rsa.PrivateKey, purpose KeyPurpose) (*PublicParams, []*KeyShare, error) {
+	n, e, d, m, err := splittableKey(l, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DealFromKey(random, l, k, n, e, d, m, purpose)
+}
+
+// DealFromPKCS8PEM will split the RSA private key in the PKCS #8 PEM block data as DealFromRSAKey does
+func DealFromPKCS8PEM(random io.Reader, l, k int, data []byte, purpose KeyPurpose) (*PublicParams, []*KeyShare, error) {
+	key, err := ParsePKCS8PEM(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DealFromRSAKey(random, l, k, key, purpose)
+}
+
+// ThresholdProtocolSetupFromRSAKey will initialise settings and setup data structures for an existing RSA key
+// l is amount of players, k is amount of signatures needed
+func ThresholdProtocolSetupFromRSAKey(l, k int, key *rsa.PrivateKey) (*ThresholdProtocolData, error) {
+	params, keyShares, err := DealFromRSAKey(nil, l, k, key, PurposeSigning)
+	if err != nil {
+		return nil, err
+	}
+	return NewThresholdProtocolData(params, keyShares), nil
+}
+
+// ParsePKCS8PEM parses the RSA private key in the PKCS #8 PEM block data
+func ParsePKCS8PEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block of type %q found", PKCS8PEMType)
+	}
+	if block.Type != PKCS8PEMType {
+		return nil, fmt.Errorf("expected PEM block of type %q, got %q", PKCS8PEMType, block.Type)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected an RSA key, got %T", ErrUnsuitableKey, parsed)
+	}
+	return key, nil
+}
+
+// splittableKey checks that the key can be split for l players, and returns n, e, d and m for DealFromKey, where
+// d is reduced modulo m
+func splittableKey(l int, key *rsa.PrivateKey) (*big.Int, *big.Int, *big.Int, *big.Int, error) {
+	if err := key.Validate(); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("%w: %w", ErrUnsuitableKey, err)
+	}
+	if len(key.Primes) != 2 {
+		return nil, nil, nil, nil, fmt.Errorf("%w: N must have 2 prime factors, not %d", ErrUnsuitableKey, len(key.Primes))
+	}
+	e := big.NewInt(int64(key.E))
+	if !e.ProbablyPrime(20) {
+		return nil, nil, nil, nil, fmt.Errorf("%w: public exponent %d is not prime", ErrUnsuitableKey, key.E)
+	}
+	if e.Cmp(big.NewInt(int64(l))) <= 0 {
+		return nil, nil, nil, nil, fmt.Errorf("%w: public exponent %d must be larger than %d players", ErrUnsuitableKey, key.E, l)
+	}
+	m := big.NewInt(1)
+	for _, p := range key.Primes {
+		pPrime := new(big.Int).Rsh(p, 1)
+		if !pPrime.ProbablyPrime(20) {
+			return nil, nil, nil, nil, fmt.Errorf("%w: the prime factors of N are not safe primes", ErrUnsuitableKey)
+		}
+		m.Mul(m, pPrime)
+	}
+	d := new(big.Int).Mod(key.D, m)
+	return key.N, e, d, m, nil
+}
@@ -0,0 +1,80 @@
+package SecureMPC
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha3"
+	_ "crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// "MessageHash" is about the hash function with which messages are hashed before they are encoded and signed.
+// It is chosen with the key and recorded in the public parameters. Messages can be given as strings, as bytes or
+// as an io.Reader, which is hashed while reading, so messages do not have to fit in memory. Applications that
+// hash the message themselves use the functions taking the digest instead.
+
+// MessageHashes are the hash functions messages can be hashed with
+var MessageHashes = []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512, crypto.SHA3_256}
+
+// digestInfoPrefixes are the DER encodings of the DigestInfo of each hash without the digest itself, as used by
+// EMSA-PKCS1-v1_5
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05,
+		0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05,
+		0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05,
+		0x00, 0x04, 0x40},
+	crypto.SHA3_256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x08, 0x05,
+		0x00, 0x04, 0x20},
+}
+
+// HashMessage hashes msg with the message hash of the public parameters
+func (params *PublicParams) HashMessage(msg []byte) ([]byte, error) {
+	return params.HashReader(bytes.NewReader(msg))
+}
+
+// HashReader hashes everything read from r with the message hash of the public parameters
+func (params *PublicParams) HashReader(r io.Reader) ([]byte, error) {
+	if err := checkMessageHash(params.Hash); err != nil {
+		return nil, err
+	}
+	h := params.Hash.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// checkDigest checks that digest can be the message hash of the public parameters
+func (params *PublicParams) checkDigest(digest []byte) error {
+	if err := checkMessageHash(params.Hash); err != nil {
+		return err
+	}
+	if len(digest) != params.Hash.Size() {
+		return fmt.Errorf("digest must be %d bytes for %v", params.Hash.Size(), params.Hash)
+	}
+	return nil
+}
+
+func checkMessageHash(hash crypto.Hash) error {
+	for _, h := range MessageHashes {
+		if h == hash {
+			return nil
+		}
+	}
+	return errors.New("unsupported message hash " + hash.String())
+}
+
+// parseMessageHash returns the message hash with the given name, as returned by crypto.Hash.String
+func parseMessageHash(name string) (crypto.Hash, error) {
+	for _, h := range MessageHashes {
+		if h.String() == name {
+			return h, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown message hash %q", name)
+}
@@ -0,0 +1,84 @@
+package SecureMPC
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha3"
+	_ "crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// "ProofParams" are the sizes used in the proof that a signature share is correct. In the proof a player picks
+// a random r and publishes z = s_i*c + r, where s_i is its key share and c the challenge. For z to hide s_i, r
+// must be larger than s_i*c by a statistical slack, so r is picked below 2^(ShareBits + HashBits + Slack) and any
+// honest z is below 2^(ShareBits + HashBits + Slack + 1), which is enforced when verifying. The challenge c is
+// computed from a Transcript of the proof with Hash.
+
+// DefaultProofSlack is the default statistical slack of the proofs in bits
+const DefaultProofSlack = 128
+
+// ProofParams are the sizes used in the proofs of the signature shares
+type ProofParams struct {
+	ShareBits int         // ShareBits bounds the bit length of the key shares, which depends on how they were dealt
+	HashBits  int         // HashBits is the bit length of the challenge c, at most the hash output length
+	Slack     int         // Slack is the statistical security in bits with which z hides s_i*c
+	Hash      crypto.Hash // Hash is the hash function of the proof transcript
+}
+
+// DefaultProofParams returns the proof parameters for key shares below N, using the full hash output as challenge
+func DefaultProofParams(n *big.Int) ProofParams {
+	return ProofParams{
+		ShareBits: n.BitLen(),
+		HashBits:  8 * crypto.SHA256.Size(),
+		Slack:     DefaultProofSlack,
+		Hash:      crypto.SHA256,
+	}
+}
+
+// Check returns an error if the proof parameters can not be used
+func (pp ProofParams) Check() error {
+	if pp.ShareBits < 1 || pp.HashBits < 1 || pp.Slack < 0 {
+		return errors.New("proof parameters must be positive")
+	}
+	if !isProofHash(pp.Hash) {
+		return fmt.Errorf("hash %v can not be used for proofs", pp.Hash)
+	}
+	if pp.HashBits > 8*pp.Hash.Size() {
+		return errors.New("challenge is longer than the hash output")
+	}
+	return nil
+}
+
+// proofHashes are the hash functions the proof transcript can use
+var proofHashes = []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512, crypto.SHA3_256, crypto.SHA3_384, crypto.SHA3_512}
+
+func isProofHash(hash crypto.Hash) bool {
+	for _, h := range proofHashes {
+		if h == hash {
+			return hash.Available()
+		}
+	}
+	return false
+}
+
+// parseProofHash returns the proof hash with the given name, as returned by crypto.Hash.String
+func parseProofHash(name string) (crypto.Hash, error) {
+	for _, h := range proofHashes {
+		if h.String() == name {
+			return h, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown proof hash %q", name)
+}
+
+// randomnessBound is the exclusive bound of the random r of the proof
+func (pp ProofParams) randomnessBound() *big.Int {
+	return new(big.Int).Lsh(One, uint(pp.ShareBits+pp.HashBits+pp.Slack))
+}
+
+// zInRange tells if z can be the response of an honest player
+func (pp ProofParams) zInRange(z *big.Int) bool {
+	return z.Sign() >= 0 && z.BitLen() <= pp.ShareBits+pp.HashBits+pp.Slack+1
+}
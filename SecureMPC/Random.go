@@ -0,0 +1,60 @@
+package SecureMPC
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// "Random" is about where the randomness comes from. Every function that picks random values reads them from an
+// io.Reader, which is crypto/rand.Reader unless another one is given. With a deterministic stream, keys, key
+// shares and proofs can be reproduced, which is what tests and known answer vectors need. Such a stream must
+// never be used for a real key.
+//
+// The reader is only used through rand.Int and io.ReadFull. crypto/rand.Prime ignores its reader in recent Go
+// versions, so primes are picked by randomPrime instead.
+//
+// The salts and nonces of sealed key shares and the exponents of batch verification always come from
+// crypto/rand.Reader: a repeated nonce breaks AES-GCM, and known exponents let invalid shares pass a batch.
+
+// randomOrDefault returns random, or crypto/rand.Reader if random is nil
+func randomOrDefault(random io.Reader) io.Reader {
+	if random == nil {
+		return rand.Reader
+	}
+	return random
+}
+
+// randomPrime returns a prime of exactly bits bits read from random. As crypto/rand.Prime, the top two bits are
+// set, so the product of two such primes has 2*bits bits.
+func randomPrime(random io.Reader, bits int) (*big.Int, error) {
+	if bits < 2 {
+		return nil, errors.New("prime size must be at least 2 bits")
+	}
+	random = randomOrDefault(random)
+	b := make([]byte, (bits+7)/8)
+	for {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return nil, randomnessError(err)
+		}
+		setPrimeBits(b, bits)
+		p := new(big.Int).SetBytes(b)
+		if p.ProbablyPrime(20) {
+			return p, nil
+		}
+	}
+}
+
+// setPrimeBits turns the random bytes b into an odd number of exactly bits bits with the top two bits set
+func setPrimeBits(b []byte, bits int) {
+	excess := uint(len(b)*8 - bits)
+	b[0] &= byte(0xff >> excess)
+	if excess < 7 {
+		b[0] |= 0xc0 >> excess
+	} else {
+		b[0] |= 0x01
+		b[1] |= 0x80
+	}
+	b[len(b)-1] |= 1
+}
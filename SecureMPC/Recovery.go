@@ -0,0 +1,283 @@
+package SecureMPC
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// "Recovery" lets k helpers give a player that lost its key share exactly that key share back, without anybody
+// learning the signing key or another key share. The key shares are values of an integer polynomial, so with
+// lambda_i = Delta * L_i(j) for the helpers
+//
+//	sum_i lambda_i * s_i = Delta * s_j
+//
+// Helper i can not send lambda_i * s_i to player j, since that would reveal s_i. Instead every helper first sends
+// each other helper a random mask r_(i,i'), and then sends player j the masked value
+//
+//	w_i = lambda_i * s_i + sum_i' r_(i,i') - sum_i' r_(i',i)
+//
+// The masks cancel out in the sum of the w_i, which is Delta * s_j, but each single w_i looks random. The masks are
+// larger than lambda_i * s_i by a statistical slack, such that they hide it. Each helper publishes V^r_(i,i') for
+// the masks it picked, so the receiving helpers can check them and player j can check each w_i against
+// v_i^lambda_i. The recovered key share is accepted only if V^s_j is the verification key of player j.
+
+// RecoveryMasks are the random masks a helper sends to the other helpers
+type RecoveryMasks struct {
+	Dealer      int              // Dealer is the id of the helper that picked the masks
+	Lost        int              // Lost is the id of the player whose key share is recovered
+	Epoch       int              // Epoch is the epoch of the key
+	Helpers     []int            // Helpers are the ids of the helpers, in increasing order
+	Commitments map[int]*big.Int // Commitments[i] = V^r_i for the mask r_i sent to helper i
+	masks       map[int]*big.Int // masks[i] = r_i must only be sent to helper i
+}
+
+// RecoveryShare is the masked contribution of a helper, which must only be sent to the player that lost its key
+// share
+type RecoveryShare struct {
+	Helper int      // Helper is the id of the helper
+	Lost   int      // Lost is the id of the player whose key share is recovered
+	Value  *big.Int // Value is lambda_i * s_i plus the masks sent minus the masks received
+}
+
+// DealRecoveryMasks picks the masks this player sends to the other helpers, for recovering the key share of
+// player lost. helpers are the ids of at least K players helping, which must include this player.
+func (p *ThresholdPlayer) DealRecoveryMasks(lost int, helpers []int) (*RecoveryMasks, error) {
+	params, keyShare := p.state()
+	if keyShare.Epoch != params.Epoch {
+		return nil, ErrStaleKeyShare
+	}
+	helpers, err := checkRecoveryHelpers(params, lost, helpers)
+	if err != nil {
+		return nil, err
+	}
+	if !containsId(helpers, p.Id) {
+		return nil, fmt.Errorf("player %d is not helping to recover", p.Id)
+	}
+	bound := recoveryMaskBound(params, lost, helpers)
+	commitments := map[int]*big.Int{}
+	masks := map[int]*big.Int{}
+	for _, id := range helpers {
+		if id == p.Id {
+			continue
+		}
+		mask, err := p.randomInt(bound)
+		if err != nil {
+			return nil, err
+		}
+		masks[id] = mask
+		commitments[id] = new(big.Int).Exp(params.V, mask, params.N)
+	}
+	return &RecoveryMasks{
+		Dealer:      p.Id,
+		Lost:        lost,
+		Epoch:       params.Epoch,
+		Helpers:     helpers,
+		Commitments: commitments,
+		masks:       masks,
+	}, nil
+}
+
+// MaskFor returns the mask which the helper must send privately to helper id
+func (m *RecoveryMasks) MaskFor(id int) *big.Int {
+	return m.masks[id]
+}
+
+// CreateRecoveryShare computes the masked contribution of this player to the recovery. masks are the masks of all
+// helpers, including the ones this player dealt, and received maps the id of each other helper to the mask it
+// sent this player. If any of the received masks is invalid, the error names the helpers that sent them.
+func (p *ThresholdPlayer) CreateRecoveryShare(masks []*RecoveryMasks, received map[int]*big.Int) (*RecoveryShare, error) {
+	params, keyShare := p.state()
+	if keyShare.Epoch != params.Epoch {
+		return nil, ErrStaleKeyShare
+	}
+	if err := checkRecoveryMasks(params, masks); err != nil {
+		return nil, err
+	}
+	lost, helpers := masks[0].Lost, masks[0].Helpers
+	invalid := []int{}
+	value := new(big.Int).Mul(lagrangeCoefficientAt(params.Delta, lost, p.Id, helpers), keyShare.secret)
+	for _, m := range masks {
+		if m.Dealer == p.Id {
+			if m.masks == nil {
+				return nil, errors.New("masks dealt by this player are missing")
+			}
+			for _, mask := range m.masks {
+				value.Add(value, mask)
+			}
+			continue
+		}
+		mask := received[m.Dealer]
+		if mask == nil || new(big.Int).Exp(params.V, mask, params.N).Cmp(m.Commitments[p.Id]) != 0 {
+			invalid = append(invalid, m.Dealer)
+			continue
+		}
+		value.Sub(value, mask)
+	}
+	if len(invalid) > 0 {
+		return nil, &PlayerError{Err: ErrInvalidRecovery, PlayerIds: invalid}
+	}
+	return &RecoveryShare{
+		Helper: p.Id,
+		Lost:   lost,
+		Value:  value,
+	}, nil
+}
+
+// RecoverKeyShare puts together the key share of the player that lost it from the masks and the recovery shares
+// of the helpers. If any of the recovery shares is invalid, the error names the helpers that sent them.
+func RecoverKeyShare(params *PublicParams, masks []*RecoveryMasks, shares []*RecoveryShare) (*KeyShare, error) {
+	if err := checkRecoveryMasks(params, masks); err != nil {
+		return nil, err
+	}
+	lost, helpers := masks[0].Lost, masks[0].Helpers
+	byHelper := map[int]*RecoveryShare{}
+	for _, share := range shares {
+		if share.Lost != lost || !containsId(helpers, share.Helper) || byHelper[share.Helper] != nil {
+			return nil, fmt.Errorf("%w: unexpected recovery share of player %d", ErrInvalidRecovery, share.Helper)
+		}
+		byHelper[share.Helper] = share
+	}
+	invalid := []int{}
+	sum := big.NewInt(0)
+	for _, i := range helpers {
+		share := byHelper[i]
+		if share == nil || share.Value == nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		if new(big.Int).Exp(params.V, share.Value, params.N).Cmp(expectedRecoveryCommitment(params, masks, i)) != 0 {
+			invalid = append(invalid, i)
+			continue
+		}
+		sum.Add(sum, share.Value)
+	}
+	if len(invalid) > 0 {
+		return nil, &PlayerError{Err: ErrInvalidRecovery, PlayerIds: invalid}
+	}
+	secret, remainder := new(big.Int).QuoRem(sum, params.Delta, new(big.Int))
+	if remainder.Sign() != 0 || secret.Sign() <= 0 ||
+		new(big.Int).Exp(params.V, secret, params.N).Cmp(params.VerificationKeys[lost]) != 0 {
+		return nil, &PlayerError{Err: ErrInvalidRecovery, PlayerIds: helpers}
+	}
+	return &KeyShare{
+		Id:     lost,
+		Epoch:  params.Epoch,
+		secret: secret,
+	}, nil
+}
+
+// RecoverShare runs a recovery between the helpers and returns the key share of player lost
+func RecoverShare(data *ThresholdProtocolData, lost int, helpers []int) (*KeyShare, error) {
+	masks := make([]*RecoveryMasks, 0, len(helpers))
+	for _, id := range helpers {
+		if id < 1 || id > data.L {
+			return nil, fmt.Errorf("%w: player %d is not a player of the key", ErrInvalidRecovery, id)
+		}
+		m, err := data.Participants[id].DealRecoveryMasks(lost, helpers)
+		if err != nil {
+			return nil, &PlayerError{Err: err, PlayerIds: []int{id}}
+		}
+		masks = append(masks, m)
+	}
+	shares := make([]*RecoveryShare, 0, len(helpers))
+	for _, id := range helpers {
+		received := map[int]*big.Int{}
+		for _, m := range masks {
+			if m.Dealer != id {
+				received[m.Dealer] = m.MaskFor(id)
+			}
+		}
+		share, err := data.Participants[id].CreateRecoveryShare(masks, received)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return RecoverKeyShare(data.PublicParams, masks, shares)
+}
+
+// checkRecoveryHelpers checks that at least k distinct players other than lost help, and returns their ids in
+// increasing order
+func checkRecoveryHelpers(params *PublicParams, lost int, helpers []int) ([]int, error) {
+	if lost < 1 || lost > params.L {
+		return nil, fmt.Errorf("%w: player %d is not a player of the key", ErrInvalidRecovery, lost)
+	}
+	sorted := append([]int{}, helpers...)
+	sort.Ints(sorted)
+	for i, id := range sorted {
+		if id < 1 || id > params.L || id == lost || (i > 0 && sorted[i-1] == id) {
+			return nil, fmt.Errorf("%w: invalid helpers %v", ErrInvalidRecovery, helpers)
+		}
+	}
+	if len(sorted) < params.K {
+		return nil, fmt.Errorf("%w: recovery needs at least %d helpers", ErrInvalidRecovery, params.K)
+	}
+	return sorted, nil
+}
+
+// checkRecoveryMasks checks that the masks are for the same recovery, that there are masks from each helper and
+// that each helper committed to a mask for every other helper
+func checkRecoveryMasks(params *PublicParams, masks []*RecoveryMasks) error {
+	if len(masks) == 0 {
+		return fmt.Errorf("%w: no masks", ErrInvalidRecovery)
+	}
+	first := masks[0]
+	helpers, err := checkRecoveryHelpers(params, first.Lost, first.Helpers)
+	if err != nil {
+		return err
+	}
+	dealt := map[int]bool{}
+	for _, m := range masks {
+		if !containsId(helpers, m.Dealer) || dealt[m.Dealer] {
+			return fmt.Errorf("%w: unexpected masks of player %d", ErrInvalidRecovery, m.Dealer)
+		}
+		dealt[m.Dealer] = true
+		valid := m.Lost == first.Lost && m.Epoch == params.Epoch && equalIds(m.Helpers, helpers) &&
+			len(m.Commitments) == len(helpers)-1
+		for _, id := range helpers {
+			if id != m.Dealer {
+				commitment := m.Commitments[id]
+				valid = valid && commitment != nil && commitment.Sign() > 0 && commitment.Cmp(params.N) < 0
+			}
+		}
+		if !valid {
+			return &PlayerError{Err: ErrInvalidRecovery, PlayerIds: []int{m.Dealer}}
+		}
+	}
+	if len(dealt) != len(helpers) {
+		return fmt.Errorf("%w: every helper must deal masks", ErrInvalidRecovery)
+	}
+	return nil
+}
+
+// expectedRecoveryCommitment computes V^w_i for the recovery share of helper i from its verification key and the
+// commitments to the masks
+func expectedRecoveryCommitment(params *PublicParams, masks []*RecoveryMasks, i int) *big.Int {
+	lost, helpers := masks[0].Lost, masks[0].Helpers
+	lambda := lagrangeCoefficientAt(params.Delta, lost, i, helpers)
+	expected := new(big.Int).Exp(params.VerificationKeys[i], lambda, params.N)
+	if expected == nil {
+		return big.NewInt(0)
+	}
+	for _, m := range masks {
+		if m.Dealer == i {
+			for _, commitment := range m.Commitments {
+				expected.Mul(expected, commitment).Mod(expected, params.N)
+			}
+		} else {
+			inverse := new(big.Int).ModInverse(m.Commitments[i], params.N)
+			if inverse == nil {
+				return big.NewInt(0)
+			}
+			expected.Mul(expected, inverse).Mod(expected, params.N)
+		}
+	}
+	return expected
+}
+
+// recoveryMaskBound is the bound of the masks, which is larger than lambda_i * s_i by the statistical slack
+func recoveryMaskBound(params *PublicParams, lost int, helpers []int) *big.Int {
+	return new(big.Int).Lsh(maxWeightedShare(params, lost, helpers), statisticalSecurity)
+}
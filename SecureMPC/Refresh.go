@@ -0,0 +1,296 @@
+package SecureMPC
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// "Refresh" re-randomizes the key shares without changing N, E or the private exponent, such that shares stolen
+// before a refresh are of no use together with shares stolen after it. Every player deals a random integer
+// polynomial g_j of degree k-1 with g_j(0) = 0 and sends g_j(i) to player i, who adds the received values to its
+// key share:
+//
+//	s'_i = s_i + sum_j g_j(i)
+//
+// The sum of the g_j is zero at 0, so the Lagrange combination with the integer coefficients Delta*L_i(0) still
+// gives the same multiple of d. The polynomials are over the integers, since nobody knows the order m of the
+// group. Their coefficients are larger than Delta * Scale * N by a statistical slack, which bounds how much the
+// shares of k-1 players change for another d, so old and new shares together say nothing about d. The bound does
+// not depend on the shares, so they only grow by the values added in each refresh, and the share bits of the
+// proofs by at most one bit per refresh. Every player refuses a value above what a polynomial with such
+// coefficients gives.
+//
+// The dealer publishes V^a_t for the coefficients a_t, so player i can check the value it got from the dealer
+// and everybody can compute the new verification keys V^s'_i. The refresh moves the key to the next epoch, after
+// which key shares and signature shares of the previous epoch are refused.
+
+// RefreshDeal is the contribution of a dealer to a refresh
+type RefreshDeal struct {
+	Dealer      int        // Dealer is the id of the player who dealt the zero polynomial
+	Epoch       int        // Epoch is the epoch of the key the refresh starts from
+	Commitments []*big.Int // Commitments[t-1] = V^a_t for the coefficients a_1..a_(k-1) of the zero polynomial
+	shares      []*big.Int // shares[i] = g(i) must only be sent to player i
+}
+
+// DealRefresh creates the zero polynomial of this player for a refresh of the key shares
+func (p *ThresholdPlayer) DealRefresh() (*RefreshDeal, error) {
+	params, keyShare := p.state()
+	if keyShare.Epoch != params.Epoch {
+		return nil, ErrStaleKeyShare
+	}
+	bound := refreshCoefficientBound(params)
+	poly := &BigPolynomial{constant: big.NewInt(0), coefs: make([]*big.Int, params.K-1)}
+	commitments := make([]*big.Int, params.K-1)
+	for t := range poly.coefs {
+		coef, err := p.randomInt(bound)
+		if err != nil {
+			return nil, err
+		}
+		poly.coefs[t] = coef
+		commitments[t] = new(big.Int).Exp(params.V, coef, params.N)
+	}
+	shares := make([]*big.Int, params.L+1)
+	for i := 1; i <= params.L; i++ {
+		shares[i] = poly.evalInteger(big.NewInt(int64(i)))
+	}
+	return &RefreshDeal{
+		Dealer:      p.Id,
+		Epoch:       params.Epoch,
+		Commitments: commitments,
+		shares:      shares,
+	}, nil
+}
+
+// ShareFor returns the value of the zero polynomial which the dealer must send privately to player id
+func (deal *RefreshDeal) ShareFor(id int) *big.Int {
+	if id < 1 || id >= len(deal.shares) {
+		return nil
+	}
+	return deal.shares[id]
+}
+
+// VerifyRefreshShare checks that share is the value of the zero polynomial of the deal for player id, that is
+// V^share = prod_t Commitments[t-1]^(id^t), and that it is not above refreshValueBound. A larger value could push
+// the key share of the player beyond the share bits of the proofs, such that it could never sign again.
+func VerifyRefreshShare(deal *RefreshDeal, id int, share *big.Int, params *PublicParams) bool {
+	if share == nil || share.Sign() < 0 || share.Cmp(refreshValueBound(params)) > 0 || deal.Epoch != params.Epoch ||
+		len(deal.Commitments) != params.K-1 {
+		return false
+	}
+	expected := deal.commitmentAt(id, params)
+	return expected != nil && new(big.Int).Exp(params.V, share, params.N).Cmp(expected) == 0
+}
+
+// commitmentAt computes V^g(id) from the commitments of the deal, where V^g(0) = 1
+func (deal *RefreshDeal) commitmentAt(id int, params *PublicParams) *big.Int {
+	return evalCommitments(append([]*big.Int{One}, deal.Commitments...), id, params)
+}
+
+// evalCommitments computes V^f(id) = prod_t commitments[t]^(id^t) from the commitments V^a_t of the coefficients
+// of a polynomial f. It returns nil if a commitment is not in Z_N*.
+func evalCommitments(commitments []*big.Int, id int, params *PublicParams) *big.Int {
+	result := big.NewInt(1)
+	idpowt := big.NewInt(1)
+	for _, commitment := range commitments {
+		if commitment == nil || commitment.Sign() <= 0 || commitment.Cmp(params.N) >= 0 {
+			return nil
+		}
+		result.Mul(result, new(big.Int).Exp(commitment, idpowt, params.N)).Mod(result, params.N)
+		idpowt.Mul(idpowt, big.NewInt(int64(id)))
+	}
+	return result
+}
+
+// RefreshedParams computes the public parameters of the next epoch from the deals of a refresh. Every player
+// must take part in the refresh with exactly one deal.
+func RefreshedParams(params *PublicParams, deals []*RefreshDeal) (*PublicParams, error) {
+	if err := checkRefreshDeals(params, deals); err != nil {
+		return nil, err
+	}
+	verificationKeys := make([]*big.Int, params.L+1)
+	for i := 1; i <= params.L; i++ {
+		vk := new(big.Int).Set(params.VerificationKeys[i])
+		for _, deal := range deals {
+			commitment := deal.commitmentAt(i, params)
+			if commitment == nil {
+				return nil, &PlayerError{Err: ErrInvalidRefresh, PlayerIds: []int{deal.Dealer}}
+			}
+			vk.Mul(vk, commitment).Mod(vk, params.N)
+		}
+		verificationKeys[i] = vk
+	}
+	refreshed := *params
+	refreshed.VerificationKeys = verificationKeys
+	refreshed.Epoch = params.Epoch + 1
+	refreshed.Proof.ShareBits = refreshedShareBound(params).BitLen()
+	return &refreshed, nil
+}
+
+// ApplyRefresh adds the values received from the dealers to the key share of this player and moves it to the
+// refreshed public parameters. shares maps the id of each dealer to the value it sent this player. Nothing is
+// changed if any of the values is invalid, in which case the error names the dealers that sent them.
+func (p *ThresholdPlayer) ApplyRefresh(refreshed *PublicParams, deals []*RefreshDeal, shares map[int]*big.Int) error {
+	pending, err := p.prepareRefresh(refreshed, deals, shares)
+	if err != nil {
+		return err
+	}
+	return p.commitRefresh(pending)
+}
+
+// pendingRefresh is a checked refresh of a player that is not applied yet, with what is needed to undo it
+type pendingRefresh struct {
+	params          *PublicParams // params are the public parameters before the refresh
+	keyShare        *KeyShare     // keyShare is the key share before the refresh
+	refreshed       *PublicParams
+	refreshedShare  *KeyShare
+	knownSignatures map[string]map[int]*SignatureShare // knownSignatures are the signature shares dropped by the refresh
+}
+
+// prepareRefresh checks the values received from the dealers and computes the refreshed key share, without
+// changing the player
+func (p *ThresholdPlayer) prepareRefresh(refreshed *PublicParams, deals []*RefreshDeal, shares map[int]*big.Int) (*pendingRefresh, error) {
+	params, keyShare := p.state()
+	if keyShare.Epoch != params.Epoch {
+		return nil, ErrStaleKeyShare
+	}
+	if err := checkRefreshDeals(params, deals); err != nil {
+		return nil, err
+	}
+	invalid := []int{}
+	secret := new(big.Int).Set(keyShare.secret)
+	for _, deal := range deals {
+		share := shares[deal.Dealer]
+		if !VerifyRefreshShare(deal, p.Id, share, params) {
+			invalid = append(invalid, deal.Dealer)
+			continue
+		}
+		secret.Add(secret, share)
+	}
+	if len(invalid) > 0 {
+		return nil, &PlayerError{Err: ErrInvalidRefresh, PlayerIds: invalid}
+	}
+	if refreshed.Epoch != params.Epoch+1 || refreshed.N.Cmp(params.N) != 0 || refreshed.V.Cmp(params.V) != 0 {
+		return nil, errors.New("refreshed public parameters do not follow the current ones")
+	}
+	if new(big.Int).Exp(refreshed.V, secret, refreshed.N).Cmp(refreshed.VerificationKeys[p.Id]) != 0 {
+		return nil, errors.New("refreshed verification key does not match the refreshed key share")
+	}
+	return &pendingRefresh{
+		params:         params,
+		keyShare:       keyShare,
+		refreshed:      refreshed,
+		refreshedShare: &KeyShare{Id: p.Id, Epoch: refreshed.Epoch, secret: secret},
+	}, nil
+}
+
+// commitRefresh applies a prepared refresh, unless the key share changed since it was prepared
+func (p *ThresholdPlayer) commitRefresh(pending *pendingRefresh) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keyShare != pending.keyShare || p.Params != pending.params {
+		return errors.New("key share changed during the refresh")
+	}
+	p.keyShare = pending.refreshedShare
+	p.Params = pending.refreshed
+	// Signature shares of the previous epoch can not be combined with the ones of the new epoch
+	pending.knownSignatures = p.knownSignatures
+	p.knownSignatures = map[string]map[int]*SignatureShare{}
+	return nil
+}
+
+// rollbackRefresh undoes a committed refresh, unless the key share changed since
+func (p *ThresholdPlayer) rollbackRefresh(pending *pendingRefresh) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keyShare != pending.refreshedShare || p.Params != pending.refreshed {
+		return
+	}
+	p.keyShare = pending.keyShare
+	p.Params = pending.params
+	p.knownSignatures = pending.knownSignatures
+}
+
+// KeyShare returns the current key share of the player, which must be stored again after a refresh
+func (p *ThresholdPlayer) KeyShare() *KeyShare {
+	_, keyShare := p.state()
+	return keyShare
+}
+
+// RefreshShares runs a refresh between all participants, who end up with new key shares and public parameters.
+// If the refresh fails, all participants keep their key shares of the current epoch.
+func RefreshShares(data *ThresholdProtocolData) error {
+	deals := make([]*RefreshDeal, data.L)
+	for i := 1; i <= data.L; i++ {
+		deal, err := data.Participants[i].DealRefresh()
+		if err != nil {
+			return &PlayerError{Err: err, PlayerIds: []int{i}}
+		}
+		deals[i-1] = deal
+	}
+	refreshed, err := RefreshedParams(data.PublicParams, deals)
+	if err != nil {
+		return err
+	}
+	// Every player checks its values before any of them moves to the new epoch, so a failing refresh leaves all
+	// of them in the old one
+	pending := make([]*pendingRefresh, data.L+1)
+	for i := 1; i <= data.L; i++ {
+		shares := map[int]*big.Int{}
+		for _, deal := range deals {
+			shares[deal.Dealer] = deal.ShareFor(i)
+		}
+		if pending[i], err = data.Participants[i].prepareRefresh(refreshed, deals, shares); err != nil {
+			return err
+		}
+	}
+	for i := 1; i <= data.L; i++ {
+		if err := data.Participants[i].commitRefresh(pending[i]); err != nil {
+			for j := 1; j < i; j++ {
+				data.Participants[j].rollbackRefresh(pending[j])
+			}
+			return &PlayerError{Err: err, PlayerIds: []int{i}}
+		}
+	}
+	data.PublicParams = refreshed
+	return nil
+}
+
+// checkRefreshDeals checks that there is exactly one deal of the current epoch from each player
+func checkRefreshDeals(params *PublicParams, deals []*RefreshDeal) error {
+	dealt := map[int]bool{}
+	for _, deal := range deals {
+		if deal.Dealer < 1 || deal.Dealer > params.L || dealt[deal.Dealer] {
+			return fmt.Errorf("%w: unexpected deal of player %d", ErrInvalidRefresh, deal.Dealer)
+		}
+		if deal.Epoch != params.Epoch || len(deal.Commitments) != params.K-1 {
+			return &PlayerError{Err: ErrInvalidRefresh, PlayerIds: []int{deal.Dealer}}
+		}
+		dealt[deal.Dealer] = true
+	}
+	if len(dealt) != params.L {
+		return fmt.Errorf("%w: every player must deal in a refresh", ErrInvalidRefresh)
+	}
+	return nil
+}
+
+// refreshCoefficientBound is the bound of the coefficients of the zero polynomials, which is larger than
+// Delta * Scale * N by the statistical slack. It is the same in every epoch.
+func refreshCoefficientBound(params *PublicParams) *big.Int {
+	return new(big.Int).Mul(integerCoefficientBound(params.N, params.L), params.scale())
+}
+
+// refreshValueBound bounds the values of a zero polynomial at the ids of the players, which are at most
+// (k-1) * bound * l^(k-1)
+func refreshValueBound(params *PublicParams) *big.Int {
+	lpowk := new(big.Int).Exp(big.NewInt(int64(params.L)), big.NewInt(int64(params.K-1)), nil)
+	bound := new(big.Int).Mul(refreshCoefficientBound(params), lpowk)
+	return bound.Mul(bound, big.NewInt(int64(params.K-1)))
+}
+
+// refreshedShareBound bounds the key shares after the refresh, to which each of the l zero polynomials adds at
+// most refreshValueBound
+func refreshedShareBound(params *PublicParams) *big.Int {
+	added := new(big.Int).Mul(refreshValueBound(params), big.NewInt(int64(params.L)))
+	return added.Add(added, new(big.Int).Lsh(One, uint(params.Proof.ShareBits)))
+}
@@ -0,0 +1,329 @@
+package SecureMPC
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// "Reshare" hands a threshold key over to a new committee of l' players with threshold k', keeping N and E, so
+// the signer set can change without a new key. A committee of at least k players of the old key takes part. Old
+// player i computes c_i = lambda_i * s_i with its Lagrange coefficient lambda_i = Delta * L_i(0) for the committee,
+// such that the sum of the c_i is Delta * Scale * d in the exponent, and shares Delta' * c_i with a random integer
+// polynomial g_i of degree k'-1. New player j adds up the values it gets into its key share s'_j = sum_i g_i(j),
+// such that for any k' new players
+//
+//	sum_j Delta' * L'_j(0) * s'_j = Delta' * Delta' * Delta * Scale * d
+//
+// Combining signature shares of the new key thus gives x^(4 Delta'^2 Delta' Delta Scale d), which is why Delta'
+// and the Delta of the old key are multiplied into the Scale of the new public parameters. As in DealFromKey,
+// the extra Delta' keeps s'_j from giving away the sum of the c_i modulo j, since g_i(j) = g_i(0) mod j. The
+// combination exponent 4 Delta'^2 Scale must be prime to e, which for a prime e means e > l'.
+//
+// The polynomials are over the integers, since nobody knows the order m of the group. Their coefficients are
+// larger than Delta' * c_i by a statistical slack, and the linear coefficient is at least the bound of the
+// coefficients, so the new key shares are positive although some c_i are negative. For k' = 1 the polynomials
+// are constant and every new player gets the sum of the Delta' * c_i, which is positive on its own. Every dealer publishes
+// V^(Delta' c_i), which everybody can check against its verification key as v_i^(Delta' lambda_i), and V^a_t for
+// the other coefficients, so new players can check the values they get and everybody can compute the new
+// verification keys. The reshared key is in the epoch after the old one, so key shares of the old committee are
+// refused by the new key.
+
+// ReshareDeal is the contribution of an old player to a resharing
+type ReshareDeal struct {
+	Dealer      int        // Dealer is the id of the old player who dealt the polynomial
+	Epoch       int        // Epoch is the epoch of the old key
+	Committee   []int      // Committee are the ids of the old players taking part, in increasing order
+	L           int        // L is the number of players of the new key
+	K           int        // K is the threshold of the new key
+	Commitments []*big.Int // Commitments[t] = V^a_t for the coefficients a_0 = Delta' * c_i, a_1, ..., a_(k'-1) of the polynomial
+	shares      []*big.Int // shares[j] = g(j) must only be sent to new player j
+}
+
+// DealReshare creates the polynomial of this player for resharing the key to l players with threshold k. committee
+// are the ids of the old players taking part, which must be at least K and include this player.
+func (p *ThresholdPlayer) DealReshare(committee []int, l, k int) (*ReshareDeal, error) {
+	params, keyShare := p.state()
+	if keyShare.Epoch != params.Epoch {
+		return nil, ErrStaleKeyShare
+	}
+	committee, err := checkReshareCommittee(params, committee)
+	if err != nil {
+		return nil, err
+	}
+	if !containsId(committee, p.Id) {
+		return nil, fmt.Errorf("player %d is not in the resharing committee", p.Id)
+	}
+	if err := checkReshareConfig(params, l, k); err != nil {
+		return nil, err
+	}
+	lambda := lagrangeCoefficient(params.Delta, p.Id, committee)
+	lambda.Mul(lambda, new(big.Int).MulRange(1, int64(l)))
+	bound := reshareCoefficientBound(params, committee, l)
+	poly := &BigPolynomial{constant: new(big.Int).Mul(lambda, keyShare.secret), coefs: make([]*big.Int, k-1)}
+	for t := range poly.coefs {
+		coef, err := p.randomInt(bound)
+		if err != nil {
+			return nil, err
+		}
+		poly.coefs[t] = coef
+	}
+	if k > 1 {
+		poly.coefs[0].Add(poly.coefs[0], bound)
+	}
+	commitments := make([]*big.Int, k)
+	commitments[0] = new(big.Int).Exp(params.V, poly.constant, params.N)
+	for t, coef := range poly.coefs {
+		commitments[t+1] = new(big.Int).Exp(params.V, coef, params.N)
+	}
+	shares := make([]*big.Int, l+1)
+	for j := 1; j <= l; j++ {
+		shares[j] = poly.evalInteger(big.NewInt(int64(j)))
+	}
+	return &ReshareDeal{
+		Dealer:      p.Id,
+		Epoch:       params.Epoch,
+		Committee:   committee,
+		L:           l,
+		K:           k,
+		Commitments: commitments,
+		shares:      shares,
+	}, nil
+}
+
+// ShareFor returns the value of the polynomial which the dealer must send privately to new player id
+func (deal *ReshareDeal) ShareFor(id int) *big.Int {
+	if id < 1 || id >= len(deal.shares) {
+		return nil
+	}
+	return deal.shares[id]
+}
+
+// VerifyReshareShare checks that share is the value of the polynomial of the deal for new player id, that is
+// V^share = prod_t Commitments[t]^(id^t). params are the public parameters of the old key.
+func VerifyReshareShare(deal *ReshareDeal, id int, share *big.Int, params *PublicParams) bool {
+	if share == nil || deal.Epoch != params.Epoch || id < 1 || id > deal.L || len(deal.Commitments) != deal.K {
+		return false
+	}
+	expected := evalCommitments(deal.Commitments, id, params)
+	return expected != nil && new(big.Int).Exp(params.V, share, params.N).Cmp(expected) == 0
+}
+
+// ResharedParams computes the public parameters of the new key from the deals of a resharing. Every old player of
+// the committee must have dealt exactly once.
+func ResharedParams(params *PublicParams, deals []*ReshareDeal) (*PublicParams, error) {
+	if err := checkReshareDeals(params, deals); err != nil {
+		return nil, err
+	}
+	l, k, committee := deals[0].L, deals[0].K, deals[0].Committee
+	verificationKeys := make([]*big.Int, l+1)
+	for j := 1; j <= l; j++ {
+		vk := big.NewInt(1)
+		for _, deal := range deals {
+			vk.Mul(vk, evalCommitments(deal.Commitments, j, params)).Mod(vk, params.N)
+		}
+		verificationKeys[j] = vk
+	}
+	reshared := newPublicParams(l, k, params.N, params.E, params.V, verificationKeys)
+	reshared.Hash = params.Hash
+	reshared.Encoding = params.Encoding
+	reshared.Purpose = params.Purpose
+	reshared.Proof = params.Proof
+	reshared.Proof.ShareBits = resharedShareBound(params, committee, l, k).BitLen()
+	reshared.Epoch = params.Epoch + 1
+	reshared.Scale = new(big.Int).Mul(reshared.Delta, params.Delta)
+	reshared.Scale.Mul(reshared.Scale, params.scale())
+	return reshared, nil
+}
+
+// ReshareKeyShare puts together the key share of new player id from the values it received from the dealers.
+// params are the public parameters of the old key and reshared the ones computed by ResharedParams from the deals.
+// shares maps the id of each dealer to the value it sent this player. If any of the values is invalid, the error
+// names the dealers that sent them.
+func ReshareKeyShare(params, reshared *PublicParams, deals []*ReshareDeal, id int, shares map[int]*big.Int) (*KeyShare, error) {
+	if err := checkReshareDeals(params, deals); err != nil {
+		return nil, err
+	}
+	if reshared.Epoch != params.Epoch+1 || reshared.N.Cmp(params.N) != 0 || reshared.V.Cmp(params.V) != 0 ||
+		id < 1 || id > reshared.L {
+		return nil, errors.New("reshared public parameters do not follow the old ones")
+	}
+	invalid := []int{}
+	secret := big.NewInt(0)
+	for _, deal := range deals {
+		share := shares[deal.Dealer]
+		if !VerifyReshareShare(deal, id, share, params) {
+			invalid = append(invalid, deal.Dealer)
+			continue
+		}
+		secret.Add(secret, share)
+	}
+	if len(invalid) > 0 {
+		return nil, &PlayerError{Err: ErrInvalidReshare, PlayerIds: invalid}
+	}
+	if secret.Sign() <= 0 || new(big.Int).Exp(reshared.V, secret, reshared.N).Cmp(reshared.VerificationKeys[id]) != 0 {
+		return nil, errors.New("reshared verification key does not match the reshared key share")
+	}
+	return &KeyShare{
+		Id:     id,
+		Epoch:  reshared.Epoch,
+		secret: secret,
+	}, nil
+}
+
+// Reshare runs a resharing between the players of the committee, which hand the key over to l new players with
+// threshold k
+func Reshare(data *ThresholdProtocolData, committee []int, l, k int) (*ThresholdProtocolData, error) {
+	deals := make([]*ReshareDeal, 0, len(committee))
+	for _, id := range committee {
+		if id < 1 || id > data.L {
+			return nil, fmt.Errorf("%w: player %d is not a player of the key", ErrInvalidReshare, id)
+		}
+		deal, err := data.Participants[id].DealReshare(committee, l, k)
+		if err != nil {
+			return nil, &PlayerError{Err: err, PlayerIds: []int{id}}
+		}
+		deals = append(deals, deal)
+	}
+	reshared, err := ResharedParams(data.PublicParams, deals)
+	if err != nil {
+		return nil, err
+	}
+	keyShares := make([]*KeyShare, l+1)
+	for j := 1; j <= l; j++ {
+		shares := map[int]*big.Int{}
+		for _, deal := range deals {
+			shares[deal.Dealer] = deal.ShareFor(j)
+		}
+		if keyShares[j], err = ReshareKeyShare(data.PublicParams, reshared, deals, j, shares); err != nil {
+			return nil, err
+		}
+	}
+	return NewThresholdProtocolData(reshared, keyShares), nil
+}
+
+// checkReshareCommittee checks that the committee consists of at least k distinct players of the key, and
+// returns its ids in increasing order
+func checkReshareCommittee(params *PublicParams, committee []int) ([]int, error) {
+	sorted := append([]int{}, committee...)
+	sort.Ints(sorted)
+	for i, id := range sorted {
+		if id < 1 || id > params.L || (i > 0 && sorted[i-1] == id) {
+			return nil, fmt.Errorf("%w: invalid committee %v", ErrInvalidReshare, committee)
+		}
+	}
+	if len(sorted) < params.K {
+		return nil, fmt.Errorf("%w: committee needs at least %d players", ErrInvalidReshare, params.K)
+	}
+	return sorted, nil
+}
+
+// checkReshareConfig checks that the key can be reshared to l players with threshold k
+func checkReshareConfig(params *PublicParams, l, k int) error {
+	if k < 1 || k > l {
+		return fmt.Errorf("%w: threshold must be between 1 and l", ErrInvalidReshare)
+	}
+	newDelta := new(big.Int).MulRange(1, int64(l))
+	twodelta := new(big.Int).Mul(Two, newDelta)
+	exponent := new(big.Int).Mul(twodelta, twodelta)
+	exponent.Mul(exponent, newDelta).Mul(exponent, params.Delta).Mul(exponent, params.scale())
+	if new(big.Int).GCD(nil, nil, exponent, params.E).Cmp(One) != 0 {
+		return fmt.Errorf("%w: e is not prime to the combination exponent of %d players", ErrInvalidReshare, l)
+	}
+	return nil
+}
+
+// checkReshareDeals checks that the deals are for the same resharing, that there is exactly one deal from each
+// player of the committee, and that each deal shares Delta' * lambda_i * s_i of its dealer
+func checkReshareDeals(params *PublicParams, deals []*ReshareDeal) error {
+	if len(deals) == 0 {
+		return fmt.Errorf("%w: no deals", ErrInvalidReshare)
+	}
+	first := deals[0]
+	committee, err := checkReshareCommittee(params, first.Committee)
+	if err != nil {
+		return err
+	}
+	if err := checkReshareConfig(params, first.L, first.K); err != nil {
+		return err
+	}
+	dealt := map[int]bool{}
+	for _, deal := range deals {
+		if !containsId(committee, deal.Dealer) || dealt[deal.Dealer] {
+			return fmt.Errorf("%w: unexpected deal of player %d", ErrInvalidReshare, deal.Dealer)
+		}
+		dealt[deal.Dealer] = true
+		if deal.Epoch != params.Epoch || deal.L != first.L || deal.K != first.K ||
+			!equalIds(deal.Committee, committee) || len(deal.Commitments) != deal.K ||
+			evalCommitments(deal.Commitments, 1, params) == nil {
+			return &PlayerError{Err: ErrInvalidReshare, PlayerIds: []int{deal.Dealer}}
+		}
+		lambda := lagrangeCoefficient(params.Delta, deal.Dealer, committee)
+		lambda.Mul(lambda, new(big.Int).MulRange(1, int64(first.L)))
+		expected := new(big.Int).Exp(params.VerificationKeys[deal.Dealer], lambda, params.N)
+		if expected == nil || deal.Commitments[0].Cmp(expected) != 0 {
+			return &PlayerError{Err: ErrInvalidReshare, PlayerIds: []int{deal.Dealer}}
+		}
+	}
+	if len(dealt) != len(committee) {
+		return fmt.Errorf("%w: every player of the committee must deal", ErrInvalidReshare)
+	}
+	return nil
+}
+
+// containsId tells if id is one of the sorted ids
+func containsId(ids []int, id int) bool {
+	i := sort.SearchInts(ids, id)
+	return i < len(ids) && ids[i] == id
+}
+
+func equalIds(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// maxWeightedShare bounds |Delta * L_i(x) * s_i| for the key shares of the players ids
+func maxWeightedShare(params *PublicParams, x int, ids []int) *big.Int {
+	bound := big.NewInt(0)
+	for _, i := range ids {
+		lambda := new(big.Int).Abs(lagrangeCoefficientAt(params.Delta, x, i, ids))
+		if lambda.Cmp(bound) > 0 {
+			bound = lambda
+		}
+	}
+	return bound.Lsh(bound, uint(params.Proof.ShareBits))
+}
+
+// reshareCoefficientBound is the bound of the coefficients of the polynomials, which is larger than the
+// Delta' * c_i by the statistical slack
+func reshareCoefficientBound(params *PublicParams, committee []int, l int) *big.Int {
+	bound := new(big.Int).Mul(maxWeightedShare(params, 0, committee), new(big.Int).MulRange(1, int64(l)))
+	return bound.Lsh(bound, statisticalSecurity)
+}
+
+// resharedShareBound bounds the key shares of the new key. Each of the polynomials is at most
+// |Delta' * c_i| + k * 2 * bound * l^(k-1) at the new ids.
+func resharedShareBound(params *PublicParams, committee []int, l, k int) *big.Int {
+	lpowk := new(big.Int).Exp(big.NewInt(int64(l)), big.NewInt(int64(k-1)), nil)
+	polyBound := new(big.Int).Mul(reshareCoefficientBound(params, committee, l), lpowk)
+	polyBound.Mul(polyBound, big.NewInt(int64(2*k)))
+	constantBound := new(big.Int).Mul(maxWeightedShare(params, 0, committee), new(big.Int).MulRange(1, int64(l)))
+	polyBound.Add(polyBound, constantBound)
+	return polyBound.Mul(polyBound, big.NewInt(int64(len(committee))))
+}
+
+// scale returns the Scale of the public parameters, which is 1 when it is not set
+func (params *PublicParams) scale() *big.Int {
+	if params.Scale == nil {
+		return One
+	}
+	return params.Scale
+}
@@ -0,0 +1,126 @@
+package SecureMPC
+
+import (
+	"math/big"
+	"sort"
+)
+
+// "RobustCombiner" creates a signature from signature shares of which some may be invalid, for example because
+// the players sending them are cheating. Every share is verified first and the invalid ones are rejected. The
+// remaining shares are combined k at a time, trying other subsets until the signature is valid. For a dealt key,
+// where N is a product of safe primes and V generates the squares, verified shares always combine, so the first
+// subset works. Keys from the distributed key generation do not have safe prime factors, and a verified share
+// may then still be off by an element of small order, so at most maxRobustSubsets subsets are tried.
+
+// maxRobustSubsets is the number of subsets of verified shares that are combined before giving up
+const maxRobustSubsets = 64
+
+// CombineResult is the outcome of a robust combination
+type CombineResult struct {
+	Signature *big.Int // Signature is the signature, or nil if it could not be created
+	Used      []int    // Used are the ids of the players whose shares make up the signature
+	Rejected  []int    // Rejected are the ids of the players whose shares failed verification
+}
+
+// CreateSignatureRobust will create the signature for the message from the valid shares among sigShares.
+// The result is returned even when creating the signature fails, so the rejected players are known. The error
+// is then a PlayerError with ErrInsufficientShares and the ids of the rejected players if fewer than k shares
+// are valid, or with ErrInvalidSignature and the ids of the first combined players if no subset that was tried
+// gives a valid signature.
+func CreateSignatureRobust(msg string, params *PublicParams, sigShares map[int]*SignatureShare) (*CombineResult, error) {
+	digest, err := params.HashMessage([]byte(msg))
+	if err != nil {
+		return &CombineResult{}, encodingError(err)
+	}
+	return CreateSignatureRobustFromDigest(digest, params, sigShares)
+}
+
+// CreateSignatureRobustFromDigest is CreateSignatureRobust for a message that is already hashed with the message
+// hash
+func CreateSignatureRobustFromDigest(digest []byte, params *PublicParams, sigShares map[int]*SignatureShare) (*CombineResult, error) {
+	x, err := params.encodeDigest(digest)
+	if err != nil {
+		return &CombineResult{}, encodingError(err)
+	}
+	return combineRobust(digest, x, params, sigShares)
+}
+
+// CreateSignatureRobustFromEncoded is CreateSignatureRobust for signature shares created by SignEncodedMsg for x,
+// the encoding of msg
+func CreateSignatureRobustFromEncoded(msg string, x *big.Int, params *PublicParams, sigShares map[int]*SignatureShare) (*CombineResult, error) {
+	digest, err := params.HashMessage([]byte(msg))
+	if err != nil {
+		return &CombineResult{}, encodingError(err)
+	}
+	if err := params.checkEncoding(digest, x); err != nil {
+		return &CombineResult{}, encodingError(err)
+	}
+	return combineRobust(digest, x, params, sigShares)
+}
+
+func combineRobust(digest []byte, x *big.Int, params *PublicParams, sigShares map[int]*SignatureShare) (*CombineResult, error) {
+	result := &CombineResult{}
+	ids := make([]int, 0, len(sigShares))
+	for id := range sigShares {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	valid := []int{}
+	for _, id := range ids {
+		share := sigShares[id]
+		// A share stored under the id of another player is rejected as well
+		if share == nil || share.id != id || !verifyEncodedShare(signatureProofDomain, digest, x, share, params) {
+			result.Rejected = append(result.Rejected, id)
+			continue
+		}
+		valid = append(valid, id)
+	}
+	if len(valid) < params.K {
+		return result, &PlayerError{Err: ErrInsufficientShares, PlayerIds: result.Rejected}
+	}
+	// Try the subsets of k valid shares in lexicographic order. If all shares are good the first one works.
+	subset := make([]int, params.K)
+	for i := range subset {
+		subset[i] = i
+	}
+	var failed error
+	for tried := 0; tried < maxRobustSubsets; tried++ {
+		shares := map[int]*SignatureShare{}
+		for _, i := range subset {
+			shares[valid[i]] = sigShares[valid[i]]
+		}
+		y, err := combineSignatureShares(signatureProofDomain, x, params, shares)
+		if err == nil {
+			result.Signature = y
+			for _, i := range subset {
+				result.Used = append(result.Used, valid[i])
+			}
+			return result, nil
+		}
+		if failed == nil {
+			failed = err
+		}
+		if !nextSubset(subset, len(valid)) {
+			break
+		}
+	}
+	return result, failed
+}
+
+// nextSubset changes subset, which are increasing indices in [0,n), to the next subset in lexicographic order.
+// It returns false if subset was the last one.
+func nextSubset(subset []int, n int) bool {
+	k := len(subset)
+	i := k - 1
+	for i >= 0 && subset[i] == n-k+i {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	subset[i]++
+	for j := i + 1; j < k; j++ {
+		subset[j] = subset[j-1] + 1
+	}
+	return true
+}
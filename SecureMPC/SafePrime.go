@@ -0,0 +1,208 @@
+package SecureMPC
+
+import (
+	"context"
+	"crypto/sha3"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// "SafePrime" finds safe primes p = 2p'+1, where p' is prime as well, which is what GeneratePrimes needs. Testing
+// random primes p' until 2p'+1 happens to be prime wastes almost all the work on p', so instead the search walks
+// through windows of candidates p', p'+2, p'+4, ... and sieves each window with the small odd primes r, dropping
+// every candidate where r divides p' or 2p'+1. Only the few survivors are tested with Miller-Rabin.
+//
+// The windows are searched in batches by several goroutines. The start of batch i is derived from a seed read once
+// from the source of randomness and i, and the safe prime of the first batch that has one is returned, so the
+// result only depends on the randomness and not on the number of goroutines or how they are scheduled.
+
+const (
+	safePrimeWindow     = 1 << 12 // safePrimeWindow is the number of candidates p' in a batch
+	safePrimeSeedSize   = 32      // safePrimeSeedSize is the size of the seed the batches are derived from
+	safePrimeSieveBound = 1 << 14 // safePrimeSieveBound bounds the small primes of the sieve
+)
+
+// SafePrimeOptions configure the search for safe primes. nil searches on GOMAXPROCS goroutines without reporting
+// progress.
+type SafePrimeOptions struct {
+	Workers  int                     // Workers bounds the goroutines searching at once, 0 means GOMAXPROCS
+	Progress func(SafePrimeProgress) // Progress is called after each batch of candidates, but never concurrently
+	Pool     *SafePrimePool          // Pool is drawn from by GeneratePrimesContext instead of the one of UseSafePrimePool
+}
+
+// SafePrimeProgress tells how far the search for safe primes has come
+type SafePrimeProgress struct {
+	Bits       int // Bits is the size of the safe primes searched for
+	Found      int // Found is the number of safe primes found so far
+	Needed     int // Needed is the number of safe primes searched for
+	Candidates int // Candidates is the number of candidates searched for the current safe prime
+}
+
+// minSafePrimeBits is the smallest size of safe primes. There is no safe prime of 4 or 5 bits with the top two
+// bits set, and 7 is the only one of 3 bits.
+const minSafePrimeBits = 6
+
+// GenerateSafePrime returns a safe prime p of exactly bits bits and the prime p' = (p-1)/2. As for randomPrime,
+// the top two bits of p are set, so the product of two such primes has 2*bits bits. If ctx is done before a safe
+// prime is found, ctx.Err() is returned. bits must be at least minSafePrimeBits.
+// random is the source of randomness, nil means crypto/rand.Reader
+func GenerateSafePrime(ctx context.Context, random io.Reader, bits int, opts *SafePrimeOptions) (*big.Int, *big.Int, error) {
+	return generateSafePrime(ctx, random, bits, opts, 0, 1)
+}
+
+// generateSafePrime searches a safe prime, reporting found of needed safe primes to the progress callback
+func generateSafePrime(ctx context.Context, random io.Reader, bits int, opts *SafePrimeOptions, found, needed int) (*big.Int, *big.Int, error) {
+	if bits < minSafePrimeBits {
+		return nil, nil, fmt.Errorf("safe prime size must be at least %d bits, not %d", minSafePrimeBits, bits)
+	}
+	if opts == nil {
+		opts = &SafePrimeOptions{}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	seed := make([]byte, safePrimeSeedSize)
+	if _, err := io.ReadFull(randomOrDefault(random), seed); err != nil {
+		return nil, nil, randomnessError(err)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	sieve := sievePrimes(bits)
+
+	var (
+		next       atomic.Int64 // next is the next batch to search
+		best       atomic.Int64 // best is the first batch known to have a safe prime
+		mu         sync.Mutex   // mu guards p, pPrime and candidates, and serializes the progress reports
+		p, pPrime  *big.Int
+		candidates int
+		wg         sync.WaitGroup
+	)
+	best.Store(math.MaxInt64)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				batch := next.Add(1) - 1
+				if batch > best.Load() || ctx.Err() != nil {
+					return
+				}
+				abandoned := func() bool {
+					return best.Load() < batch || ctx.Err() != nil
+				}
+				q, qPrime := searchSafePrimeBatch(seed, batch, bits, sieve, abandoned)
+				mu.Lock()
+				candidates += safePrimeWindow
+				if q != nil && batch < best.Load() {
+					best.Store(batch)
+					p, pPrime = q, qPrime
+				}
+				if opts.Progress != nil {
+					reported := found
+					if p != nil {
+						reported++
+					}
+					opts.Progress(SafePrimeProgress{Bits: bits, Found: reported, Needed: needed, Candidates: candidates})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// A cancelled search may have skipped batches before the best one, so its result is not used
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return p, pPrime, nil
+}
+
+// searchSafePrimeBatch searches the window of batch for a safe prime of bits bits and returns the first one,
+// or nil if there is none or abandoned returned true
+func searchSafePrimeBatch(seed []byte, batch int64, bits int, sieve []uint64, abandoned func() bool) (*big.Int, *big.Int) {
+	start := safePrimeBatchStart(seed, batch, bits-1)
+	composite := make([]bool, safePrimeWindow)
+	rem, r := new(big.Int), new(big.Int)
+	for _, prime := range sieve {
+		s := rem.Mod(start, r.SetUint64(prime)).Uint64()
+		half := (prime + 1) / 2 // half is the inverse of 2 modulo prime
+		// Candidate j is start + 2j, which prime divides if j = -s/2, and 2(start + 2j) + 1 if j = ((prime-1)/2 - s)/2
+		for _, j := range []uint64{(prime - s) * half % prime, ((prime-1)/2 + prime - s) * half % prime} {
+			for ; j < safePrimeWindow; j += prime {
+				composite[j] = true
+			}
+		}
+	}
+	candidate := new(big.Int)
+	for j := range composite {
+		if composite[j] {
+			continue
+		}
+		if abandoned() {
+			return nil, nil
+		}
+		candidate.Add(start, big.NewInt(int64(2*j)))
+		if candidate.BitLen() != bits-1 {
+			// The candidates only grow from here
+			return nil, nil
+		}
+		if !candidate.ProbablyPrime(0) {
+			continue
+		}
+		p := new(big.Int).Add(new(big.Int).Lsh(candidate, 1), One)
+		if p.ProbablyPrime(0) && candidate.ProbablyPrime(20) && p.ProbablyPrime(128) {
+			return p, new(big.Int).Set(candidate)
+		}
+	}
+	return nil, nil
+}
+
+// safePrimeBatchStart derives the first candidate p' of batch from the seed, an odd number of bits bits with the
+// top two bits set
+func safePrimeBatchStart(seed []byte, batch int64, bits int) *big.Int {
+	shake := sha3.NewSHAKE256()
+	shake.Write(seed)
+	shake.Write(binary.BigEndian.AppendUint64(nil, uint64(batch)))
+	b := make([]byte, (bits+7)/8)
+	shake.Read(b)
+	setPrimeBits(b, bits)
+	return new(big.Int).SetBytes(b)
+}
+
+// smallOddPrimes are the odd primes below safePrimeSieveBound
+var smallOddPrimes = sync.OnceValue(func() []uint64 {
+	composite := make([]bool, safePrimeSieveBound)
+	primes := []uint64{}
+	for i := 3; i < safePrimeSieveBound; i += 2 {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, uint64(i))
+		for j := i * i; j < safePrimeSieveBound; j += 2 * i {
+			composite[j] = true
+		}
+	}
+	return primes
+})
+
+// sievePrimes returns the small odd primes to sieve the candidates for safe primes of bits bits with. They are
+// smaller than every candidate p' >= 2^(bits-2), so a candidate they divide is never prime itself.
+func sievePrimes(bits int) []uint64 {
+	primes := smallOddPrimes()
+	if bits-2 >= 64 {
+		return primes
+	}
+	limit := uint64(1) << (bits - 2)
+	n := 0
+	for n < len(primes) && primes[n] < limit {
+		n++
+	}
+	return primes[:n]
+}
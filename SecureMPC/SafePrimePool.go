@@ -0,0 +1,233 @@
+package SecureMPC
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// "SafePrimePool" keeps safe primes generated ahead of time in a directory, so key generation in test suites and
+// the REPL does not have to wait for fresh ones. Every safe prime is stored in a file of its own. Taking a prime
+// first renames its file to a name of the taker's own, which only one taker can succeed in, and then reads and
+// deletes it, so every pooled prime is used at most once, even by several processes sharing the directory. A prime
+// that is taken but not used is gone as well.
+//
+// Anyone who read a pooled prime can factor the keys made from it, so production deployments should call
+// RefusePooledPrimes(true). Key generation only draws from a pool when it reads from crypto/rand.Reader, as keys
+// generated from another reader must be reproducible from it.
+
+// safePrimeFileSuffix is the suffix of the files of pooled primes
+const safePrimeFileSuffix = ".prime"
+
+// SafePrimePool is a directory of safe primes of Bits bits, see GenerateSafePrime
+type SafePrimePool struct {
+	Dir    string
+	Bits   int
+	Random io.Reader // Random is the source of randomness of the primes filled in, nil means crypto/rand.Reader
+}
+
+// OpenSafePrimePool opens the pool of safe primes of bits bits in dir, and creates dir if needed. A modulus of
+// keysize bits takes safe primes of keysize/2 bits. bits must be at least minSafePrimeBits, as for
+// GenerateSafePrime.
+func OpenSafePrimePool(dir string, bits int) (*SafePrimePool, error) {
+	if bits < minSafePrimeBits {
+		return nil, fmt.Errorf("safe prime size must be at least %d bits, not %d", minSafePrimeBits, bits)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &SafePrimePool{Dir: dir, Bits: bits}, nil
+}
+
+// Len returns the number of safe primes in the pool
+func (pool *SafePrimePool) Len() (int, error) {
+	names, err := pool.names()
+	return len(names), err
+}
+
+// Fill generates safe primes until the pool has count of them
+func (pool *SafePrimePool) Fill(ctx context.Context, count int, opts *SafePrimeOptions) error {
+	for {
+		n, err := pool.Len()
+		if err != nil || n >= count {
+			return err
+		}
+		p, _, err := GenerateSafePrime(ctx, pool.Random, pool.Bits, opts)
+		if err != nil {
+			return err
+		}
+		if err := pool.put(p); err != nil {
+			return err
+		}
+	}
+}
+
+// FillInBackground runs Fill in a goroutine, and sends its result on the returned channel
+func (pool *SafePrimePool) FillInBackground(ctx context.Context, count int, opts *SafePrimeOptions) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Fill(ctx, count, opts)
+	}()
+	return done
+}
+
+// Take removes a safe prime from the pool and returns it together with p' = (p-1)/2. If the pool is empty,
+// ErrSafePrimePoolEmpty is returned.
+func (pool *SafePrimePool) Take() (*big.Int, *big.Int, error) {
+	names, err := pool.names()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		claimed, err := pool.claim(name)
+		if errors.Is(err, os.ErrNotExist) {
+			// Somebody else took it
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := os.ReadFile(claimed)
+		if removeErr := os.Remove(claimed); err == nil {
+			err = removeErr
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		p, ok := new(big.Int).SetString(strings.TrimSpace(string(data)), 16)
+		if !ok {
+			return nil, nil, fmt.Errorf("pooled prime %s is not a hex number", name)
+		}
+		pPrime := new(big.Int).Rsh(p, 1)
+		if p.BitLen() != pool.Bits || !pPrime.ProbablyPrime(20) || !p.ProbablyPrime(20) {
+			return nil, nil, fmt.Errorf("pooled prime %s is not a safe prime of %d bits", name, pool.Bits)
+		}
+		return p, pPrime, nil
+	}
+	return nil, nil, ErrSafePrimePoolEmpty
+}
+
+// put adds the safe prime p to the pool. It is written to a temporary file first, so takers never see a partial
+// file.
+func (pool *SafePrimePool) put(p *big.Int) error {
+	name, err := pool.randomName()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(pool.Dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(p.Text(16) + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(pool.Dir, name+safePrimeFileSuffix))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// claim renames the file of a pooled prime to a name nobody else uses, which fails with os.ErrNotExist if
+// somebody else claimed it first
+func (pool *SafePrimePool) claim(name string) (string, error) {
+	suffix, err := pool.randomName()
+	if err != nil {
+		return "", err
+	}
+	claimed := filepath.Join(pool.Dir, name+".taken-"+suffix)
+	return claimed, os.Rename(filepath.Join(pool.Dir, name), claimed)
+}
+
+// names lists the files of the pooled primes of the size of the pool
+func (pool *SafePrimePool) names() ([]string, error) {
+	entries, err := os.ReadDir(pool.Dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strconv.Itoa(pool.Bits) + "-"
+	names := []string{}
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasPrefix(name, prefix) && strings.HasSuffix(name, safePrimeFileSuffix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// randomName returns a new file name for a prime of the size of the pool. The names are random, such that
+// processes sharing the pool do not pick the same one.
+func (pool *SafePrimePool) randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", randomnessError(err)
+	}
+	return strconv.Itoa(pool.Bits) + "-" + hex.EncodeToString(b), nil
+}
+
+// pooledPrimes is the pool key generation draws from, see UseSafePrimePool
+var pooledPrimes struct {
+	sync.Mutex
+	pool    *SafePrimePool
+	refused bool
+}
+
+// UseSafePrimePool makes GenerateRSAKey and Deal draw safe primes of the size of pool from it, as long as they
+// read from crypto/rand.Reader, before generating fresh ones. nil stops drawing from a pool. If pooled primes are
+// refused, ErrPooledPrimesRefused is returned.
+func UseSafePrimePool(pool *SafePrimePool) error {
+	pooledPrimes.Lock()
+	defer pooledPrimes.Unlock()
+	if pool != nil && pooledPrimes.refused {
+		return ErrPooledPrimesRefused
+	}
+	pooledPrimes.pool = pool
+	return nil
+}
+
+// RefusePooledPrimes makes key generation refuse pooled safe primes, or allow them again. Refusing stops drawing
+// from the pool set by UseSafePrimePool, and makes key generation fail with ErrPooledPrimesRefused when it is
+// given a pool in SafePrimeOptions.
+func RefusePooledPrimes(refuse bool) {
+	pooledPrimes.Lock()
+	defer pooledPrimes.Unlock()
+	pooledPrimes.refused = refuse
+	if refuse {
+		pooledPrimes.pool = nil
+	}
+}
+
+// takePooledPrime takes a safe prime of bits bits from the pool of opts, or else from the pool set by
+// UseSafePrimePool. It returns nil if key generation reads from another reader than crypto/rand.Reader, or
+// there is no such pool or prime.
+func takePooledPrime(random io.Reader, bits int, opts *SafePrimeOptions) (*big.Int, *big.Int, error) {
+	pooledPrimes.Lock()
+	pool, refused := pooledPrimes.pool, pooledPrimes.refused
+	pooledPrimes.Unlock()
+	if opts != nil && opts.Pool != nil {
+		if refused {
+			return nil, nil, ErrPooledPrimesRefused
+		}
+		pool = opts.Pool
+	}
+	if randomOrDefault(random) != rand.Reader || pool == nil || pool.Bits != bits {
+		return nil, nil, nil
+	}
+	p, pPrime, err := pool.Take()
+	if errors.Is(err, ErrSafePrimePoolEmpty) {
+		return nil, nil, nil
+	}
+	return p, pPrime, err
+}
@@ -0,0 +1,112 @@
+package SecureMPC
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// scrypt derives a key of keyLen bytes from the passphrase as in RFC 7914.
+// cost is the CPU/memory cost N, which must be a power of two larger than 1, r is the block size and p the
+// parallelization.
+func scrypt(passphrase, salt []byte, cost, r, p, keyLen int) ([]byte, error) {
+	if cost <= 1 || cost&(cost-1) != 0 {
+		return nil, errors.New("scrypt: cost must be a power of two larger than 1")
+	}
+	if r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || r > (1<<31-1)/128/p || r > (1<<31-1)/256 || cost > (1<<31-1)/128/r {
+		return nil, errors.New("scrypt: parameters are too large")
+	}
+	b, err := pbkdf2.Key(sha256.New, string(passphrase), salt, 1, p*128*r)
+	if err != nil {
+		return nil, err
+	}
+	x := make([]uint32, 32*r)
+	v := make([]uint32, 32*r*cost)
+	for i := 0; i < p; i++ {
+		scryptROMix(b[i*128*r:(i+1)*128*r], x, v, cost, r)
+	}
+	return pbkdf2.Key(sha256.New, string(passphrase), b, 1, keyLen)
+}
+
+// scryptROMix mixes the block b in place, using x as working space and v as the large memory
+func scryptROMix(b []byte, x, v []uint32, cost, r int) {
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	tmp := make([]uint32, 16)
+	y := make([]uint32, 32*r)
+	for i := 0; i < cost; i++ {
+		copy(v[i*32*r:], x)
+		scryptBlockMix(x, y, tmp, r)
+	}
+	for i := 0; i < cost; i++ {
+		// Integerify takes the first word of the last 64 byte block
+		j := int(x[(2*r-1)*16] & uint32(cost-1))
+		for k := range x {
+			x[k] ^= v[j*32*r+k]
+		}
+		scryptBlockMix(x, y, tmp, r)
+	}
+	for i, word := range x {
+		binary.LittleEndian.PutUint32(b[i*4:], word)
+	}
+}
+
+// scryptBlockMix mixes the 2r blocks of 16 words in b using Salsa20/8, y is used as working space
+func scryptBlockMix(b, y, tmp []uint32, r int) {
+	copy(tmp, b[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		for k := 0; k < 16; k++ {
+			tmp[k] ^= b[i*16+k]
+		}
+		salsa208(tmp)
+		// Even blocks go to the first half and odd blocks to the second half
+		copy(y[(i/2+(i%2)*r)*16:], tmp)
+	}
+	copy(b, y)
+}
+
+// salsa208 applies the Salsa20/8 core to the 16 words of block
+func salsa208(block []uint32) {
+	var x [16]uint32
+	copy(x[:], block)
+	for i := 0; i < 8; i += 2 {
+		x[4] ^= bits.RotateLeft32(x[0]+x[12], 7)
+		x[8] ^= bits.RotateLeft32(x[4]+x[0], 9)
+		x[12] ^= bits.RotateLeft32(x[8]+x[4], 13)
+		x[0] ^= bits.RotateLeft32(x[12]+x[8], 18)
+		x[9] ^= bits.RotateLeft32(x[5]+x[1], 7)
+		x[13] ^= bits.RotateLeft32(x[9]+x[5], 9)
+		x[1] ^= bits.RotateLeft32(x[13]+x[9], 13)
+		x[5] ^= bits.RotateLeft32(x[1]+x[13], 18)
+		x[14] ^= bits.RotateLeft32(x[10]+x[6], 7)
+		x[2] ^= bits.RotateLeft32(x[14]+x[10], 9)
+		x[6] ^= bits.RotateLeft32(x[2]+x[14], 13)
+		x[10] ^= bits.RotateLeft32(x[6]+x[2], 18)
+		x[3] ^= bits.RotateLeft32(x[15]+x[11], 7)
+		x[7] ^= bits.RotateLeft32(x[3]+x[15], 9)
+		x[11] ^= bits.RotateLeft32(x[7]+x[3], 13)
+		x[15] ^= bits.RotateLeft32(x[11]+x[7], 18)
+		x[1] ^= bits.RotateLeft32(x[0]+x[3], 7)
+		x[2] ^= bits.RotateLeft32(x[1]+x[0], 9)
+		x[3] ^= bits.RotateLeft32(x[2]+x[1], 13)
+		x[0] ^= bits.RotateLeft32(x[3]+x[2], 18)
+		x[6] ^= bits.RotateLeft32(x[5]+x[4], 7)
+		x[7] ^= bits.RotateLeft32(x[6]+x[5], 9)
+		x[4] ^= bits.RotateLeft32(x[7]+x[6], 13)
+		x[5] ^= bits.RotateLeft32(x[4]+x[7], 18)
+		x[11] ^= bits.RotateLeft32(x[10]+x[9], 7)
+		x[8] ^= bits.RotateLeft32(x[11]+x[10], 9)
+		x[9] ^= bits.RotateLeft32(x[8]+x[11], 13)
+		x[10] ^= bits.RotateLeft32(x[9]+x[8], 18)
+		x[12] ^= bits.RotateLeft32(x[15]+x[14], 7)
+		x[13] ^= bits.RotateLeft32(x[12]+x[15], 9)
+		x[14] ^= bits.RotateLeft32(x[13]+x[12], 13)
+		x[15] ^= bits.RotateLeft32(x[14]+x[13], 18)
+	}
+	for i := range block {
+		block[i] += x[i]
+	}
+}
@@ -1,10 +1,12 @@
 package SecureMPC
 
 import (
+	"crypto/rand"
 	"fmt"
+	"io"
 	"log"
 	"math"
-	"math/rand"
+	"math/big"
 	"strconv"
 )
 
@@ -15,6 +17,7 @@ type ProtocolData struct {
 	n            int       // N is the number of Participants
 	k            int       // k is the number of adversaries
 	participants []*Player // Participants contains the participating players
+	random       io.Reader // random is the source of the coefficients of the polynomials, nil means crypto/rand.Reader
 }
 
 // Player contains the information a player has and learns along the way
@@ -42,6 +45,11 @@ func (p *ProtocolData) GetNumberOfParticipants() int {
 	return p.n
 }
 
+// SetRandom sets the source of the coefficients of the polynomials, nil means crypto/rand.Reader
+func (p *ProtocolData) SetRandom(random io.Reader) {
+	p.random = random
+}
+
 // MakeProtocolData creates a ProtocolData object
 func MakeProtocolData(base, n, k int) *ProtocolData {
 	participants := make([]*Player, n+1)
@@ -73,9 +81,13 @@ func (p *Player) AssignSecret(s int) {
 	p.secret = s
 }
 
-func (p *Player) CreateShares(data ProtocolData) {
-	shares := makeShares(p.secret, data)
+func (p *Player) CreateShares(data ProtocolData) error {
+	shares, err := makeShares(p.secret, data)
+	if err != nil {
+		return err
+	}
 	p.knownShares[p.id] = shares
+	return nil
 }
 
 // DistributeSecretShares will send the shares of the secret of the player to all other corresponding Participants
@@ -122,11 +134,18 @@ func (p *Player) RecomputeSecret(id int, data ProtocolData) int {
 	return mod(sum, data.base)
 }
 
-func makeShares(s int, data ProtocolData) map[int]int {
+func makeShares(s int, data ProtocolData) (map[int]int, error) {
+	if data.base < 2 {
+		return nil, fmt.Errorf("%w: base must be at least 2, not %d", ErrInvalidParams, data.base)
+	}
 	coefs := make([]int, data.k)
 	shares := map[int]int{}
 	for i := 0; i < data.k; i++ {
-		coefs[i] = rand.Intn(100) // Should probably be secure random
+		coef, err := rand.Int(randomOrDefault(data.random), big.NewInt(int64(data.base)))
+		if err != nil {
+			return nil, randomnessError(err)
+		}
+		coefs[i] = int(coef.Int64())
 	}
 	// Could also allow custom polynomial here
 	h := Polynomial{s, coefs}
@@ -135,7 +154,7 @@ func makeShares(s int, data ProtocolData) map[int]int {
 		eval := mod(h.eval(i), data.base)
 		shares[i] = eval
 	}
-	return shares
+	return shares, nil
 }
 
 func PlaySecureMPC() {
@@ -184,7 +203,10 @@ func PlaySecureMPC() {
 	player.AssignSecret(secret)
 
 	// Create polynomial and then, compute shares of the secret
-	player.CreateShares(*protocol)
+	if err := player.CreateShares(*protocol); err != nil {
+		log.Print("Creating shares failed due to:  ", err)
+		return
+	}
 
 	// Print shares
 	var shares = player.GetShares()
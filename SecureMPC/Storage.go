@@ -0,0 +1,411 @@
+package SecureMPC
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// "Storage" is about saving the output of a key generation ceremony, so that the key does not have to be
+// generated again on every run. The public parameters are stored in the clear, while every key share is sealed
+// with AES-GCM under a key derived from a passphrase with scrypt.
+//
+// Both are stored as versioned JSON, which can be wrapped in PEM armor.
+
+// StorageVersion is the version of the stored formats written by this package
+const StorageVersion = 1
+
+// PEM block types of the armored formats
+const (
+	PublicParamsPEMType = "THRESHOLD RSA PUBLIC PARAMETERS"
+	KeySharePEMType     = "ENCRYPTED THRESHOLD RSA KEY SHARE"
+)
+
+// Default scrypt parameters used by SealKeyShare, as recommended for interactive logins in RFC 7914
+const (
+	ScryptCost            = 1 << 15
+	ScryptBlockSize       = 8
+	ScryptParallelization = 1
+)
+
+// Limits of the scrypt parameters of a stored key share, so opening it can not take unbounded memory and time.
+// maxScryptMemory bounds the 128 r N bytes scrypt allocates, which is 32 MiB for the defaults.
+const (
+	maxScryptCost      = 1 << 20
+	maxScryptBlockSize = 32
+	maxScryptParallel  = 16
+	maxScryptMemory    = 1 << 30
+)
+
+type publicParamsJSON struct {
+	Version          int              `json:"version"`
+	L                int              `json:"l"`
+	K                int              `json:"k"`
+	N                string           `json:"n"`
+	E                string           `json:"e"`
+	V                string           `json:"v"`
+	VerificationKeys []string         `json:"verificationKeys"`   // VerificationKeys[i] belongs to player i+1
+	Hash             string           `json:"hash,omitempty"`     // Hash is left out for SHA-256
+	Encoding         string           `json:"encoding,omitempty"` // Encoding is left out for EncodingRawHash
+	Proof            *proofParamsJSON `json:"proof,omitempty"`    // Proof is left out for DefaultProofParams
+	Epoch            int              `json:"epoch,omitempty"`    // Epoch is left out before the first refresh
+	Scale            string           `json:"scale,omitempty"`    // Scale is left out when it is 1
+	Purpose          string           `json:"purpose,omitempty"`  // Purpose is left out for signing keys
+}
+
+type proofParamsJSON struct {
+	ShareBits int    `json:"shareBits"`
+	HashBits  int    `json:"hashBits"`
+	Slack     int    `json:"slack"`
+	Hash      string `json:"hash"`
+}
+
+type sealedKeyShareJSON struct {
+	Version    int    `json:"version"`
+	Id         int    `json:"id"`
+	Epoch      int    `json:"epoch,omitempty"`
+	KeyID      []byte `json:"keyId"`
+	KDF        string `json:"kdf"`
+	Cost       int    `json:"cost"`
+	BlockSize  int    `json:"blockSize"`
+	Parallel   int    `json:"parallelization"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// MarshalJSON encodes the public parameters. Delta is not stored, since it is given by L.
+func (params *PublicParams) MarshalJSON() ([]byte, error) {
+	verificationKeys := make([]string, params.L)
+	for i := 1; i <= params.L; i++ {
+		verificationKeys[i-1] = params.VerificationKeys[i].Text(16)
+	}
+	encoding := ""
+	if params.Encoding != EncodingRawHash {
+		encoding = params.Encoding.String()
+	}
+	hash := ""
+	if params.Hash != crypto.SHA256 {
+		hash = params.Hash.String()
+	}
+	scale := ""
+	if params.scale().Cmp(One) != 0 {
+		scale = params.Scale.Text(16)
+	}
+	purpose := ""
+	if params.Purpose != PurposeSigning {
+		purpose = params.Purpose.String()
+	}
+	var proof *proofParamsJSON
+	if params.Proof != DefaultProofParams(params.N) {
+		proof = &proofParamsJSON{
+			ShareBits: params.Proof.ShareBits,
+			HashBits:  params.Proof.HashBits,
+			Slack:     params.Proof.Slack,
+			Hash:      params.Proof.Hash.String(),
+		}
+	}
+	return json.Marshal(&publicParamsJSON{
+		Version:          StorageVersion,
+		L:                params.L,
+		K:                params.K,
+		N:                params.N.Text(16),
+		E:                params.E.Text(16),
+		V:                params.V.Text(16),
+		VerificationKeys: verificationKeys,
+		Hash:             hash,
+		Encoding:         encoding,
+		Proof:            proof,
+		Epoch:            params.Epoch,
+		Scale:            scale,
+		Purpose:          purpose,
+	})
+}
+
+// UnmarshalJSON decodes public parameters written by MarshalJSON
+func (params *PublicParams) UnmarshalJSON(data []byte) error {
+	var stored publicParamsJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored.Version != StorageVersion {
+		return fmt.Errorf("unsupported public parameters version %d", stored.Version)
+	}
+	if stored.L < 1 || len(stored.VerificationKeys) != stored.L {
+		return errors.New("public parameters must contain a verification key for each of the l players")
+	}
+	n, err := parseBigInt(stored.N)
+	if err != nil {
+		return err
+	}
+	e, err := parseBigInt(stored.E)
+	if err != nil {
+		return err
+	}
+	v, err := parseBigInt(stored.V)
+	if err != nil {
+		return err
+	}
+	verificationKeys := make([]*big.Int, stored.L+1)
+	for i, key := range stored.VerificationKeys {
+		if verificationKeys[i+1], err = parseBigInt(key); err != nil {
+			return err
+		}
+	}
+	encoding := EncodingRawHash
+	if stored.Encoding != "" {
+		if encoding, err = ParseSignatureEncoding(stored.Encoding); err != nil {
+			return err
+		}
+	}
+	loaded := newPublicParams(stored.L, stored.K, n, e, v, verificationKeys)
+	loaded.Encoding = encoding
+	if stored.Epoch < 0 {
+		return errors.New("epoch of public parameters must not be negative")
+	}
+	loaded.Epoch = stored.Epoch
+	if stored.Scale != "" {
+		if loaded.Scale, err = parseBigInt(stored.Scale); err != nil {
+			return err
+		}
+		if loaded.Scale.Sign() <= 0 {
+			return errors.New("scale of public parameters must be positive")
+		}
+	}
+	if stored.Purpose != "" {
+		if loaded.Purpose, err = ParseKeyPurpose(stored.Purpose); err != nil {
+			return err
+		}
+	}
+	if stored.Hash != "" {
+		if loaded.Hash, err = parseMessageHash(stored.Hash); err != nil {
+			return err
+		}
+	}
+	if stored.Proof != nil {
+		hash, err := parseProofHash(stored.Proof.Hash)
+		if err != nil {
+			return err
+		}
+		loaded.Proof = ProofParams{
+			ShareBits: stored.Proof.ShareBits,
+			HashBits:  stored.Proof.HashBits,
+			Slack:     stored.Proof.Slack,
+			Hash:      hash,
+		}
+		if err := loaded.Proof.Check(); err != nil {
+			return err
+		}
+	}
+	*params = *loaded
+	return nil
+}
+
+// EncodePublicParamsPEM armors the JSON encoding of the public parameters
+func EncodePublicParamsPEM(params *PublicParams) ([]byte, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PublicParamsPEMType, Bytes: data}), nil
+}
+
+// DecodePublicParams reads public parameters, either as JSON or in PEM armor, and checks them with Validate
+func DecodePublicParams(data []byte) (*PublicParams, error) {
+	data, err := unarmor(data, PublicParamsPEMType)
+	if err != nil {
+		return nil, err
+	}
+	params := &PublicParams{}
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, err
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// SealKeyShare encrypts the key share of the key with the public parameters params under the passphrase and
+// returns the JSON encoding of the result. The key id is stored with it, so it only opens for the same key.
+func SealKeyShare(keyShare *KeyShare, passphrase []byte, params *PublicParams) ([]byte, error) {
+	if keyShare.Id < 1 || keyShare.Id > params.L {
+		return nil, fmt.Errorf("player id %d is not in range [1,%d]", keyShare.Id, params.L)
+	}
+	if keyShare.Epoch != params.Epoch {
+		return nil, ErrStaleKeyShare
+	}
+	sealed := &sealedKeyShareJSON{
+		Version:   StorageVersion,
+		Id:        keyShare.Id,
+		Epoch:     keyShare.Epoch,
+		KeyID:     params.KeyID(),
+		KDF:       "scrypt",
+		Cost:      ScryptCost,
+		BlockSize: ScryptBlockSize,
+		Parallel:  ScryptParallelization,
+		Salt:      make([]byte, 16),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := sealed.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, err
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, keyShare.secret.Bytes(), sealed.additionalData())
+	return json.Marshal(sealed)
+}
+
+// OpenKeyShare decrypts a key share sealed by SealKeyShare, either as JSON or in PEM armor. The key share must
+// belong to the key with the public parameters params, a key share of an earlier epoch gives ErrStaleKeyShare.
+func OpenKeyShare(data []byte, passphrase []byte, params *PublicParams) (*KeyShare, error) {
+	data, err := unarmor(data, KeySharePEMType)
+	if err != nil {
+		return nil, err
+	}
+	var sealed sealedKeyShareJSON
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, err
+	}
+	if sealed.Version != StorageVersion {
+		return nil, fmt.Errorf("unsupported key share version %d", sealed.Version)
+	}
+	if sealed.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation function %q", sealed.KDF)
+	}
+	if sealed.Cost > maxScryptCost || sealed.BlockSize > maxScryptBlockSize || sealed.Parallel > maxScryptParallel {
+		return nil, errors.New("scrypt parameters of key share are too large")
+	}
+	if 128*sealed.BlockSize*sealed.Cost > maxScryptMemory {
+		return nil, errors.New("scrypt parameters of key share need too much memory")
+	}
+	if sealed.Id < 1 || sealed.Id > params.L {
+		return nil, fmt.Errorf("player id %d of key share is not in range [1,%d]", sealed.Id, params.L)
+	}
+	if sealed.Epoch < 0 {
+		return nil, errors.New("epoch of key share must not be negative")
+	}
+	if !bytes.Equal(sealed.KeyID, params.KeyID()) {
+		if sealed.Epoch != params.Epoch {
+			return nil, ErrStaleKeyShare
+		}
+		return nil, errors.New("key share belongs to a different key")
+	}
+	aead, err := sealed.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+	secret, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, sealed.additionalData())
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted key share")
+	}
+	return &KeyShare{
+		Id:     sealed.Id,
+		Epoch:  sealed.Epoch,
+		secret: new(big.Int).SetBytes(secret),
+	}, nil
+}
+
+// EncodeKeySharePEM seals the key share like SealKeyShare and armors the result
+func EncodeKeySharePEM(keyShare *KeyShare, passphrase []byte, params *PublicParams) ([]byte, error) {
+	data, err := SealKeyShare(keyShare, passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: KeySharePEMType, Bytes: data}), nil
+}
+
+// WritePublicParamsFile stores the public parameters in PEM armor at path
+func WritePublicParamsFile(path string, params *PublicParams) error {
+	data, err := EncodePublicParamsPEM(params)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadPublicParamsFile reads public parameters stored by WritePublicParamsFile
+func ReadPublicParamsFile(path string) (*PublicParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodePublicParams(data)
+}
+
+// WriteKeyShareFile seals the key share under the passphrase and stores it in PEM armor at path, readable only
+// by the owner
+func WriteKeyShareFile(path string, keyShare *KeyShare, passphrase []byte, params *PublicParams) error {
+	data, err := EncodeKeySharePEM(keyShare, passphrase, params)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadKeyShareFile reads and opens a key share of the key with the public parameters params stored by
+// WriteKeyShareFile
+func ReadKeyShareFile(path string, passphrase []byte, params *PublicParams) (*KeyShare, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenKeyShare(data, passphrase, params)
+}
+
+// aead derives the AES-256-GCM key from the passphrase with the scrypt parameters of the sealed key share
+func (sealed *sealedKeyShareJSON) aead(passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt(passphrase, sealed.Salt, sealed.Cost, sealed.BlockSize, sealed.Parallel, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the version, id, epoch, key id and key derivation parameters to the ciphertext, so they
+// can not be changed without the decryption failing
+func (sealed *sealedKeyShareJSON) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d|%d|%d|%s|%s|%d|%d|%d", sealed.Version, sealed.Id, sealed.Epoch,
+		hex.EncodeToString(sealed.KeyID), sealed.KDF, sealed.Cost, sealed.BlockSize, sealed.Parallel))
+}
+
+// unarmor removes the PEM armor of the given type if there is one, otherwise data is returned unchanged
+func unarmor(data []byte, pemType string) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return data, nil
+	}
+	if block.Type != pemType {
+		return nil, fmt.Errorf("expected PEM block of type %q, got %q", pemType, block.Type)
+	}
+	return block.Bytes, nil
+}
+
+func parseBigInt(s string) (*big.Int, error) {
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return x, nil
+}
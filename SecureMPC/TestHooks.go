@@ -0,0 +1,31 @@
+package SecureMPC
+
+import (
+	"SecureMPC/internal/testhooks"
+	"math/big"
+)
+
+// "TestHooks" lets the tests in Tests fix values that are random otherwise, such as the randomness of the proofs
+// of the known answer vectors, and check internals against published test vectors. The hooks take a testhooks.Key, which only packages of this module can name, so
+// they are not part of the API.
+
+// SignHashOfMsgWithR is SignHashOfMsg with r as the randomness of the proof. The signature share is not
+// remembered. r must never be used twice, as two proofs with the same r reveal the key share.
+func (p *ThresholdPlayer) SignHashOfMsgWithR(_ testhooks.Key, msg string, r *big.Int) (*SignatureShare, error) {
+	params, keyShare := p.state()
+	digest, err := params.HashMessage([]byte(msg))
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	x, err := params.encodeDigest(digest)
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	return p.createShareWithR(params, keyShare, signatureProofDomain, digest, x, r)
+}
+
+// Scrypt exposes the scrypt key derivation of the key share files, so it can be checked against the test vectors
+// of RFC 7914
+func Scrypt(_ testhooks.Key, passphrase, salt []byte, cost, r, p, keyLen int) ([]byte, error) {
+	return scrypt(passphrase, salt, cost, r, p, keyLen)
+}
@@ -0,0 +1,128 @@
+package SecureMPC
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+)
+
+// "ThresholdDecryption" uses the same scheme as "ThresholdRSA" for decryption instead of signing. A ciphertext c
+// encrypted with rsa.EncryptOAEP under (N, E), using SHA-256 for both the hash and MGF1, can only be decrypted
+// when k players cooperate:
+//
+//	1. Every player computes the decryption share c^(2 Delta s_i) with the same proof as a signature share
+//	2. The shares are combined exactly like signature shares, giving m = c^d
+//	3. m is unpadded with EME-OAEP
+//
+// Note that the players learn nothing about the plaintext from the shares, but they should only help decrypting
+// ciphertexts they are meant to, since anyone seeing k shares learns the plaintext.
+//
+// A decryption share of c is the same number as a signature share of x = c, so a key that decrypts anything for
+// anybody would also sign anything for them. Signing and decryption keys must never be shared: a decryption key
+// is dealt with PurposeDecryption, see Deal, and players refuse to sign with it, as they refuse to decrypt with a
+// signing key.
+
+// DecryptionShare is the share of a player of the decryption of a ciphertext. It has the same proof of
+// correctness and wire format as a SignatureShare, where the digest is the message hash of the ciphertext. The proof
+// is made in its own domain and only keys dealt for decryption create decryption shares, see KeyPurpose.
+type DecryptionShare struct {
+	SignatureShare
+}
+
+// DecryptShare will create the decryption share of this player for the ciphertext
+func (p *ThresholdPlayer) DecryptShare(ciphertext []byte) (*DecryptionShare, error) {
+	params, keyShare := p.state()
+	c, digest, err := ciphertextToInt(ciphertext, params)
+	if err != nil {
+		return nil, err
+	}
+	share, err := p.createShare(params, keyShare, decryptionProofDomain, digest, c)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptionShare{*share}, nil
+}
+
+// VerifyDecryptionShare checks the proof of the decryption share for the ciphertext
+func VerifyDecryptionShare(ciphertext []byte, decryptionShare *DecryptionShare, params *PublicParams) bool {
+	c, digest, err := ciphertextToInt(ciphertext, params)
+	if err != nil || decryptionShare == nil {
+		return false
+	}
+	return verifyEncodedShare(decryptionProofDomain, digest, c, &decryptionShare.SignatureShare, params)
+}
+
+// CombineDecryptionShares will decrypt the ciphertext from k decryption shares and remove the OAEP padding with
+// the given label
+func CombineDecryptionShares(ciphertext, label []byte, params *PublicParams, decryptionShares map[int]*DecryptionShare) ([]byte, error) {
+	c, _, err := ciphertextToInt(ciphertext, params)
+	if err != nil {
+		return nil, err
+	}
+	shares := map[int]*SignatureShare{}
+	for id, share := range decryptionShares {
+		if share == nil {
+			return nil, &PlayerError{Err: ErrInvalidShare, PlayerIds: []int{id}}
+		}
+		shares[id] = &share.SignatureShare
+	}
+	m, err := combineSignatureShares(decryptionProofDomain, c, params, shares)
+	if err != nil {
+		return nil, err
+	}
+	return decodeOAEP(m.FillBytes(make([]byte, params.modulusSize())), label)
+}
+
+// ciphertextToInt checks that the ciphertext is a number in Z_N and returns it together with its digest
+func ciphertextToInt(ciphertext []byte, params *PublicParams) (*big.Int, []byte, error) {
+	if len(ciphertext) != params.modulusSize() {
+		return nil, nil, fmt.Errorf("%w: ciphertext must be as long as the modulus", ErrInvalidCiphertext)
+	}
+	c := new(big.Int).SetBytes(ciphertext)
+	if c.Sign() == 0 || c.Cmp(params.N) >= 0 {
+		return nil, nil, fmt.Errorf("%w: ciphertext is not in range [1,N-1]", ErrInvalidCiphertext)
+	}
+	digest, err := params.HashMessage(ciphertext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, digest, nil
+}
+
+// decodeOAEP is EME-OAEP decoding of RFC 8017 with SHA-256 and MGF1 with SHA-256. Every failure gives the same
+// error, so the failures can not be told apart.
+func decodeOAEP(em, label []byte) ([]byte, error) {
+	hLen := sha256.Size
+	if len(em) < 2*hLen+2 {
+		return nil, ErrDecryption
+	}
+	labelHash := sha256.Sum256(label)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+	seedMask := mgf1(crypto.SHA256, db, hLen)
+	for i := range seed {
+		seed[i] ^= seedMask[i]
+	}
+	dbMask := mgf1(crypto.SHA256, seed, len(db))
+	for i := range db {
+		db[i] ^= dbMask[i]
+	}
+	valid := subtle.ConstantTimeByteEq(em[0], 0) & subtle.ConstantTimeCompare(db[:hLen], labelHash[:])
+	// DB = lHash || PS || 0x01 || M, where PS are zero bytes. Find the 0x01 without branching on the bytes.
+	index := 0
+	lookingForIndex := 1
+	invalidPadding := 0
+	for i := hLen; i < len(db); i++ {
+		isZero := subtle.ConstantTimeByteEq(db[i], 0)
+		isOne := subtle.ConstantTimeByteEq(db[i], 1)
+		index = subtle.ConstantTimeSelect(lookingForIndex&isOne, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(isOne, 0, lookingForIndex)
+		invalidPadding = subtle.ConstantTimeSelect(lookingForIndex&^isZero, 1, invalidPadding)
+	}
+	if valid&^invalidPadding&^lookingForIndex != 1 {
+		return nil, ErrDecryption
+	}
+	return db[index+1:], nil
+}
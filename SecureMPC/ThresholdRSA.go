@@ -1,10 +1,16 @@
 package SecureMPC
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/rand"
-	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"runtime"
+	"sort"
+	"sync"
 )
 
 // "ThresholdRSA" is a "k out of l threshold signature scheme" and about using shamir secret sharing scheme as in
@@ -20,120 +26,488 @@ type SignatureShare struct {
 	z         *big.Int
 	c         *big.Int
 	id        int
+	keyID     []byte   // keyID is the id of the key, see PublicParams.KeyID
+	digest    []byte   // digest is the hash of the signed message
+	vprime    *big.Int // vprime and xprime are the commitments of the proof, which allow batch verification.
+	xprime    *big.Int // They are nil for shares decoded from version 1 of the wire format.
 }
 
-type ThresholdProtocolData struct {
-	L                int // L is the number of Participants
-	K                int
+// PublicParams contains everything about the threshold key that may be published. Every player and combiner
+// works from these together with, for players, their own KeyShare.
+type PublicParams struct {
+	L                int      // L is the number of players
+	K                int      // K is the number of signature shares needed for a signature
 	N                *big.Int // N is the RSA modulo
 	E                *big.Int
 	Delta            *big.Int
 	V                *big.Int
-	Participants     []*ThresholdPlayer // Participants contains the participating players
-	VerificationKeys []*big.Int
-	GCDa             *big.Int
-	GCDb             *big.Int
+	VerificationKeys []*big.Int        // VerificationKeys[i] = V^s_i is used to check the signature shares of player i
+	Hash             crypto.Hash       // Hash is the hash function messages are hashed with, one of MessageHashes
+	Encoding         SignatureEncoding // Encoding is how the hash of a message is encoded before signing
+	Proof            ProofParams       // Proof are the sizes used in the proofs of the signature shares
+	Epoch            int               // Epoch counts the refreshes of the key shares, see RefreshShares
+	Scale            *big.Int          // Scale is the factor by which the shared secret is larger than d, see DealFromKey and Reshare
+	Purpose          KeyPurpose        // Purpose is whether the key signs or decrypts, which it must never both do
+}
+
+// KeyPurpose is what a threshold key is dealt for. A decryption share of c is the same number c^(2 Delta s_i) as
+// a signature share of x = c, so a key that decrypts for anybody also signs anything for them. Thus every key has
+// exactly one purpose, which is part of its KeyID, and players refuse to use it for the other one.
+type KeyPurpose int
+
+const (
+	PurposeSigning    KeyPurpose = iota // PurposeSigning keys only create signature shares
+	PurposeDecryption                   // PurposeDecryption keys only create decryption shares
+)
+
+func (purpose KeyPurpose) String() string {
+	switch purpose {
+	case PurposeSigning:
+		return "signing"
+	case PurposeDecryption:
+		return "decryption"
+	}
+	return fmt.Sprintf("KeyPurpose(%d)", int(purpose))
+}
+
+// ParseKeyPurpose returns the purpose with the given name, as returned by String
+func ParseKeyPurpose(name string) (KeyPurpose, error) {
+	for _, purpose := range []KeyPurpose{PurposeSigning, PurposeDecryption} {
+		if purpose.String() == name {
+			return purpose, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key purpose %q", name)
+}
+
+// KeyShare is the secret key share of a single player. It must only be known by the player with the same Id.
+type KeyShare struct {
+	Id     int
+	Epoch  int // Epoch is the epoch of the public parameters the key share belongs to
+	secret *big.Int
+}
+
+// ThresholdProtocolData contains all players of a threshold key, so they can be run and talk together in a
+// single process
+type ThresholdProtocolData struct {
+	*PublicParams
+	Participants []*ThresholdPlayer // Participants contains the participating players
+	Workers      int                // Workers bounds the goroutines signing and verifying at once, 0 means GOMAXPROCS
 }
 
-// Player contains the information a player has and learns along the way
+// Player contains the information a player has and learns along the way. Its methods may be called from several
+// goroutines at once; the signature shares it knows are read with KnownMessages and KnownShares. Random must be set
+// before that, the reads from it are serialized, so it need not be safe for concurrent use.
 type ThresholdPlayer struct {
-	secretKeyShare  *big.Int                           // secretKeyShare is the secure info to be shared
+	mu              sync.RWMutex                       // mu guards keyShare, knownSignatures and Params
+	randomMu        sync.Mutex                         // randomMu serializes the reads from Random
+	keyShare        *KeyShare                          // keyShare is the secure info to be shared
 	Id              int                                // Id is the identifier of this player
-	KnownSignatures map[string]map[int]*SignatureShare // This is a map from string messages to a map, that maps indices to signatures
-	Data            *ThresholdProtocolData
+	knownSignatures map[string]map[int]*SignatureShare // This is a map from string messages to a map, that maps indices to signatures
+	Params          *PublicParams
+	Random          io.Reader // Random is the source of randomness of the proofs and deals, nil means crypto/rand.Reader
 }
 
 // ThresholdProtocolSetup will initialise settings and setup data structures
 // l is amount of players, k is amount of signatures needed
-func ThresholdProtocolSetup(l, k, keysize int) *ThresholdProtocolData {
-	n, e, d, m := GenerateRSAKey(keysize)
-	return ThresholdProtocolSetupFromKey(l, k, n, e, d, m)
+func ThresholdProtocolSetup(l, k, keysize int) (*ThresholdProtocolData, error) {
+	params, keyShares, err := Deal(nil, l, k, keysize, PurposeSigning)
+	if err != nil {
+		return nil, err
+	}
+	return NewThresholdProtocolData(params, keyShares), nil
+}
+
+func ThresholdProtocolSetupFromKey(l, k int, n, e, d, m *big.Int) (*ThresholdProtocolData, error) {
+	params, keyShares, err := DealFromKey(nil, l, k, n, e, d, m, PurposeSigning)
+	if err != nil {
+		return nil, err
+	}
+	return NewThresholdProtocolData(params, keyShares), nil
 }
 
-func ThresholdProtocolSetupFromKey(l, k int, n, e, d, m *big.Int) *ThresholdProtocolData {
-	poly := GenerateRandomBigPolynomial(d, m, k-1)
-	secretKeyShares := GenerateSecretShares(poly, m, l)
-	v := GenerateRandomQuadratic(n)
+// Deal will, as a trusted dealer, generate an RSA key and split it into key shares for l players, such that k
+// of them are needed to sign. The public parameters may be published, but key share i must only be given to
+// player i. keyShares[0] is unused. random is the source of randomness, nil means crypto/rand.Reader. purpose
+// is what the key is dealt for, which is part of its KeyID and can not be changed afterwards.
+func Deal(random io.Reader, l, k, keysize int, purpose KeyPurpose) (*PublicParams, []*KeyShare, error) {
+	n, e, d, m, err := GenerateRSAKey(random, keysize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DealFromKey(random, l, k, n, e, d, m, purpose)
+}
+
+// DealFromKey will split the private exponent d into key shares as Deal does. m is the order of the squares
+// modulo N, of which d must be the inverse of e.
+//
+// Delta * d is shared with shareInteger, an integer polynomial rather than one modulo m, such that Delta * L_i(j)
+// combines key shares to Delta * s_j exactly, which lets players help recovering a lost key share, see
+// RecoverShare. Sharing d itself would give away d mod i with s_i, while Delta * d is a multiple of i. The extra
+// Delta is the Scale of the public parameters.
+//
+// The key shares are only returned once the public parameters are valid and SelfTest signed with them.
+func DealFromKey(random io.Reader, l, k int, n, e, d, m *big.Int, purpose KeyPurpose) (*PublicParams, []*KeyShare, error) {
+	if err := checkThreshold(l, k); err != nil {
+		return nil, nil, err
+	}
+	if err := checkPublicExponent(l, e); err != nil {
+		return nil, nil, err
+	}
+	if new(big.Int).Mod(new(big.Int).Mul(e, d), m).Cmp(One) != 0 {
+		return nil, nil, errors.New("d is not the inverse of e modulo m")
+	}
+	secretKeyShares, err := shareInteger(random, d, n, l, k)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := GenerateRandomQuadratic(random, n)
+	if err != nil {
+		return nil, nil, err
+	}
 	verificationKeys := GenerateVerificationKeys(secretKeyShares, v, n)
-	participants := make([]*ThresholdPlayer, l+1)
-	data := &ThresholdProtocolData{
+	params := newPublicParams(l, k, n, e, v, verificationKeys)
+	params.Scale = new(big.Int).Set(params.Delta)
+	params.Proof.ShareBits = integerShareBound(n, l, k, 1).BitLen()
+	params.Purpose = purpose
+	keyShares := newKeyShares(secretKeyShares)
+	if err := SelfTest(random, params, keyShares, selfTestSubsets); err != nil {
+		return nil, nil, err
+	}
+	return params, keyShares, nil
+}
+
+// newPublicParams puts together the public parameters, no matter if the key shares were made by a dealer or by
+// the players themselves
+func newPublicParams(l, k int, n, e, v *big.Int, verificationKeys []*big.Int) *PublicParams {
+	return &PublicParams{
 		L:                l,
 		K:                k,
 		N:                n,
 		E:                e,
 		Delta:            new(big.Int).MulRange(1, int64(l)), // computes factorial of l
 		V:                v,
-		Participants:     nil,
 		VerificationKeys: verificationKeys,
+		Hash:             crypto.SHA256,
+		Proof:            DefaultProofParams(n),
+		Scale:            big.NewInt(1),
 	}
-	for i := 1; i <= l; i++ {
-		emptymap := map[string]map[int]*SignatureShare{}
-		participants[i] = &ThresholdPlayer{
-			secretKeyShare:  secretKeyShares[i],
-			Id:              i,
-			KnownSignatures: emptymap,
-			Data:            data,
+}
+
+func newKeyShares(secretKeyShares []*big.Int) []*KeyShare {
+	keyShares := make([]*KeyShare, len(secretKeyShares))
+	for i := 1; i < len(secretKeyShares); i++ {
+		keyShares[i] = &KeyShare{
+			Id:     i,
+			secret: secretKeyShares[i],
 		}
 	}
-	data.Participants = participants
-	twodelta := new(big.Int).Mul(data.Delta, Two)
-	fourdeltasquared := new(big.Int).Mul(twodelta, twodelta)
+	return keyShares
+}
+
+// NewKeyShare creates the key share of player id in the given epoch from its secret, for key shares that were
+// dealt by another implementation or are read from test vectors
+func NewKeyShare(id, epoch int, secret *big.Int) *KeyShare {
+	return &KeyShare{
+		Id:     id,
+		Epoch:  epoch,
+		secret: new(big.Int).Set(secret),
+	}
+}
+
+// Secret returns a copy of the secret of the key share, for handing it to another implementation. It must be
+// kept as secret as the key share itself.
+func (keyShare *KeyShare) Secret() *big.Int {
+	return new(big.Int).Set(keyShare.secret)
+}
+
+// NewThresholdPlayer creates the player owning the key share, which only knows its own share and the public
+// parameters
+func NewThresholdPlayer(params *PublicParams, keyShare *KeyShare) *ThresholdPlayer {
+	return &ThresholdPlayer{
+		keyShare:        keyShare,
+		Id:              keyShare.Id,
+		knownSignatures: map[string]map[int]*SignatureShare{},
+		Params:          params,
+	}
+}
+
+// state returns the public parameters and the key share the player currently works with
+func (p *ThresholdPlayer) state() (*PublicParams, *KeyShare) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.Params, p.keyShare
+}
+
+// KnownShares returns a copy of the signature shares of msg the player knows, by player id
+func (p *ThresholdPlayer) KnownShares(msg string) map[int]*SignatureShare {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	shares := map[int]*SignatureShare{}
+	for id, share := range p.knownSignatures[msg] {
+		shares[id] = share
+	}
+	return shares
+}
+
+// KnownMessages returns the messages the player knows signature shares of, in increasing order
+func (p *ThresholdPlayer) KnownMessages() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	messages := make([]string, 0, len(p.knownSignatures))
+	for msg := range p.knownSignatures {
+		messages = append(messages, msg)
+	}
+	sort.Strings(messages)
+	return messages
+}
+
+// randomInt returns a uniform random number in [0, bound) read from Random
+func (p *ThresholdPlayer) randomInt(bound *big.Int) (*big.Int, error) {
+	p.randomMu.Lock()
+	defer p.randomMu.Unlock()
+	r, err := rand.Int(randomOrDefault(p.Random), bound)
+	if err != nil {
+		return nil, randomnessError(err)
+	}
+	return r, nil
+}
+
+// NewThresholdProtocolData creates a player for each of the key shares
+func NewThresholdProtocolData(params *PublicParams, keyShares []*KeyShare) *ThresholdProtocolData {
+	participants := make([]*ThresholdPlayer, params.L+1)
+	for i := 1; i <= params.L; i++ {
+		participants[i] = NewThresholdPlayer(params, keyShares[i])
+	}
+	return &ThresholdProtocolData{
+		PublicParams: params,
+		Participants: participants,
+	}
+}
+
+// gcd returns a and b such that e'a + eb = 1, where e' = 4Delta^2 Scale
+func (params *PublicParams) gcd() (*big.Int, *big.Int) {
 	a := big.NewInt(0)
 	b := big.NewInt(0)
-	_ = new(big.Int).GCD(a, b, fourdeltasquared, data.E)
-	data.GCDa = a
-	data.GCDb = b
-	return data
+	_ = new(big.Int).GCD(a, b, params.combinationExponent(), params.E)
+	return a, b
+}
+
+// combinationExponent is e' = 4Delta^2 Scale, such that combining k signature shares of x gives x^(e'd)
+func (params *PublicParams) combinationExponent() *big.Int {
+	twodelta := new(big.Int).Mul(params.Delta, Two)
+	exponent := new(big.Int).Mul(twodelta, twodelta)
+	return exponent.Mul(exponent, params.scale())
 }
 
 // SignHashOfMsg will sign the hash of the message msg, which is the signature share of this player for given message.
 // msg is the message to be signed
-func (p *ThresholdPlayer) SignHashOfMsg(msg string) *SignatureShare {
-	data := p.Data
-	digest := sha256.Sum256([]byte(msg))
-	x := new(big.Int).SetBytes(digest[:])
+func (p *ThresholdPlayer) SignHashOfMsg(msg string) (*SignatureShare, error) {
+	params, _ := p.state()
+	digest, err := params.HashMessage([]byte(msg))
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	signatureShare, err := p.SignDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	p.remember(msg, signatureShare)
+	return signatureShare, nil
+}
+
+// SignEncodedMsg will sign x, the encoding of msg chosen by a coordinator, which is needed for randomized
+// encodings like PSS. The player first checks that x really is an encoding of msg.
+func (p *ThresholdPlayer) SignEncodedMsg(msg string, x *big.Int) (*SignatureShare, error) {
+	params, _ := p.state()
+	digest, err := params.HashMessage([]byte(msg))
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	signatureShare, err := p.SignEncodedDigest(digest, x)
+	if err != nil {
+		return nil, err
+	}
+	p.remember(msg, signatureShare)
+	return signatureShare, nil
+}
+
+// SignReader will sign the message read from r, which is hashed while reading. The signature share is not
+// remembered in knownSignatures.
+func (p *ThresholdPlayer) SignReader(r io.Reader) (*SignatureShare, error) {
+	params, _ := p.state()
+	digest, err := params.HashReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.SignDigest(digest)
+}
+
+// SignDigest will sign a message that is already hashed with the message hash of the public parameters.
+// The signature share is not remembered in knownSignatures.
+func (p *ThresholdPlayer) SignDigest(digest []byte) (*SignatureShare, error) {
+	params, keyShare := p.state()
+	x, err := params.encodeDigest(digest)
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	return p.createShare(params, keyShare, signatureProofDomain, digest, x)
+}
+
+// SignEncodedDigest is SignEncodedMsg for a message that is already hashed with the message hash
+func (p *ThresholdPlayer) SignEncodedDigest(digest []byte, x *big.Int) (*SignatureShare, error) {
+	params, keyShare := p.state()
+	if err := params.checkEncoding(digest, x); err != nil {
+		return nil, encodingError(err)
+	}
+	return p.createShare(params, keyShare, signatureProofDomain, digest, x)
+}
+
+// remember stores the signature share of this player for msg with the shares received from others
+func (p *ThresholdPlayer) remember(msg string, signatureShare *SignatureShare) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.knownSignatures[msg]) == 0 {
+		p.knownSignatures[msg] = map[int]*SignatureShare{}
+	}
+	p.knownSignatures[msg][p.Id] = signatureShare
+}
+
+// Domains of the share proofs, so that the proof of a decryption share is never accepted for a signature share
+const (
+	signatureProofDomain  = "SecureMPC threshold RSA signature share proof v1"
+	decryptionProofDomain = "SecureMPC threshold RSA decryption share proof v1"
+)
+
+// checkPurpose checks that the key is dealt for the purpose of the proof domain
+func (params *PublicParams) checkPurpose(domain string) error {
+	purpose := PurposeSigning
+	if domain == decryptionProofDomain {
+		purpose = PurposeDecryption
+	}
+	if params.Purpose != purpose {
+		return fmt.Errorf("%w: the key is dealt for %v, not %v", ErrWrongPurpose, params.Purpose, purpose)
+	}
+	return nil
+}
+
+// createShare computes x^(2 Delta s_i) together with the proof that it was computed with the secret key share
+func (p *ThresholdPlayer) createShare(data *PublicParams, keyShare *KeyShare, domain string, digest []byte, x *big.Int) (*SignatureShare, error) {
+	return p.createShareWithR(data, keyShare, domain, digest, x, nil)
+}
+
+// createShareWithR is createShare with r as the randomness of the proof, nil means r is read from Random. r must
+// never be used twice, as two proofs with the same r reveal the key share.
+func (p *ThresholdPlayer) createShareWithR(data *PublicParams, keyShare *KeyShare, domain string, digest []byte, x, r *big.Int) (*SignatureShare, error) {
+	if err := data.checkPurpose(domain); err != nil {
+		return nil, err
+	}
+	if keyShare.Epoch != data.Epoch {
+		return nil, ErrStaleKeyShare
+	}
 	twodelta := new(big.Int).Mul(Two, data.Delta)
-	exponent := new(big.Int).Mul(twodelta, p.secretKeyShare)
+	exponent := new(big.Int).Mul(twodelta, keyShare.secret)
 	xi := new(big.Int).Exp(x, exponent, data.N)
 	// Now we need to construct our proof
-	// 512 is bitlength of N, 256 is length of hash output. Division by 8 is to get it in bytes
-	securityparam := (512+2*256)/8 + 1
-	bytes := make([]byte, securityparam)
-	bytes[0] = 1
-	// Bytes is equal to exactly 2^1024 when converted to a bigint
-	// sets r to be a random number from 0 to 2^(1024)-1
-	r, _ := rand.Int(rand.Reader, new(big.Int).SetBytes(bytes))
-	vi := data.VerificationKeys[p.Id]
+	if err := data.Proof.Check(); err != nil {
+		return nil, err
+	}
+	if keyShare.secret.Sign() < 0 || keyShare.secret.BitLen() > data.Proof.ShareBits {
+		return nil, errors.New("key share is larger than the proof parameters allow")
+	}
+	// r is a random number from 0 to 2^(ShareBits+HashBits+Slack)-1
+	bound := data.Proof.randomnessBound()
+	if r == nil {
+		var err error
+		if r, err = p.randomInt(bound); err != nil {
+			return nil, err
+		}
+	} else if r.Sign() < 0 || r.Cmp(bound) >= 0 {
+		return nil, errors.New("proof randomness is out of range")
+	}
+	keyID := data.KeyID()
 	fourdelta := new(big.Int).Mul(Two, twodelta)
 	xtilde := new(big.Int).Exp(x, fourdelta, data.N)
 	xprime := new(big.Int).Exp(xtilde, r, data.N)
 	vprime := new(big.Int).Exp(data.V, r, data.N)
 	xisquared := new(big.Int).Exp(xi, Two, data.N)
-	c := HashSixBigInts(data.V, xtilde, vi, xisquared, vprime, xprime)
-	sic := new(big.Int).Mul(p.secretKeyShare, c)
+	c := data.proofChallenge(domain, keyID, digest, p.Id, xtilde, xisquared, vprime, xprime)
+	sic := new(big.Int).Mul(keyShare.secret, c)
 	z := new(big.Int).Add(sic, r)
 	signatureShare := &SignatureShare{
 		signature: xi,
 		z:         z,
 		c:         c,
 		id:        p.Id,
+		keyID:     keyID,
+		digest:    digest,
+		vprime:    vprime,
+		xprime:    xprime,
 	}
-	if len(p.KnownSignatures[msg]) == 0 {
-		p.KnownSignatures[msg] = map[int]*SignatureShare{}
+	return signatureShare, nil
+}
+
+// proofChallenge computes the challenge c of the proof of the share of player id. Besides the values of the proof
+// it binds the key, the message and the player, so a proof can not be replayed for another one of them.
+func (params *PublicParams) proofChallenge(domain string, keyID, digest []byte, id int, xtilde, xisquared, vprime, xprime *big.Int) *big.Int {
+	t := NewTranscript(params.Proof.Hash, domain)
+	t.AppendBytes("key id", keyID)
+	t.AppendBytes("digest", digest)
+	t.AppendInt("player", id)
+	t.AppendBigInt("v", params.V)
+	t.AppendBigInt("x~", xtilde)
+	t.AppendBigInt("v_i", params.VerificationKeys[id])
+	t.AppendBigInt("x_i^2", xisquared)
+	t.AppendBigInt("v'", vprime)
+	t.AppendBigInt("x'", xprime)
+	return t.Challenge("c", params.Proof.HashBits)
+}
+
+func VerifyShare(msg string, signatureShare *SignatureShare, data *PublicParams) bool {
+	digest, err := data.HashMessage([]byte(msg))
+	if err != nil {
+		return false
+	}
+	return VerifyDigestShare(digest, signatureShare, data)
+}
+
+// VerifyDigestShare verifies a signature share of a message that is already hashed with the message hash
+func VerifyDigestShare(digest []byte, signatureShare *SignatureShare, data *PublicParams) bool {
+	x, err := data.encodeDigest(digest)
+	if err != nil {
+		return false
 	}
-	p.KnownSignatures[msg][p.Id] = signatureShare
-	return signatureShare
+	return verifyEncodedShare(signatureProofDomain, digest, x, signatureShare, data)
 }
 
-func HashSixBigInts(v, x, vi, x2, vp, xp *big.Int) *big.Int {
-	toHash := v.String() + "|" + x.String() + "|" + vi.String() + "|" + x2.String() + "|" + vp.String() + "|" + xp.String()
-	digest := sha256.Sum256([]byte(toHash))
-	return new(big.Int).SetBytes(digest[:])
+// VerifyEncodedShare verifies a signature share created by SignEncodedMsg for x, the encoding of msg
+func VerifyEncodedShare(msg string, x *big.Int, signatureShare *SignatureShare, data *PublicParams) bool {
+	digest, err := data.HashMessage([]byte(msg))
+	if err != nil {
+		return false
+	}
+	return VerifyEncodedDigestShare(digest, x, signatureShare, data)
 }
 
-func VerifyShare(msg string, signatureShare *SignatureShare, data *ThresholdProtocolData) bool {
-	digest := sha256.Sum256([]byte(msg))
-	x := new(big.Int).SetBytes(digest[:])
+// VerifyEncodedDigestShare is VerifyEncodedShare for a message that is already hashed with the message hash
+func VerifyEncodedDigestShare(digest []byte, x *big.Int, signatureShare *SignatureShare, data *PublicParams) bool {
+	if data.checkEncoding(digest, x) != nil {
+		return false
+	}
+	return verifyEncodedShare(signatureProofDomain, digest, x, signatureShare, data)
+}
+
+func verifyEncodedShare(domain string, digest []byte, x *big.Int, signatureShare *SignatureShare, data *PublicParams) bool {
+	if data.checkPurpose(domain) != nil {
+		return false
+	}
+	if signatureShare.CheckRange(data) != nil || data.Proof.Check() != nil {
+		return false // Decoded shares are not range checked, and out of range values have no inverse modulo N
+	}
+	keyID := data.KeyID()
+	if !bytes.Equal(signatureShare.digest, digest) {
+		return false // The share is for another message
+	}
 	twodelta := new(big.Int).Mul(Two, data.Delta)
 	fourdelta := new(big.Int).Mul(Two, twodelta)
 	xtilde := new(big.Int).Exp(x, fourdelta, data.N)
@@ -153,110 +527,248 @@ func VerifyShare(msg string, signatureShare *SignatureShare, data *ThresholdProt
 	xprimeNoMod := new(big.Int).Mul(xic, xtildez)
 	xprime := new(big.Int).Mod(xprimeNoMod, data.N)
 	xisquared := new(big.Int).Exp(xi, Two, data.N)
-	cprime := HashSixBigInts(data.V, xtilde, vi, xisquared, vprime, xprime)
+	if signatureShare.vprime != nil && (signatureShare.vprime.Cmp(vprime) != 0 || signatureShare.xprime.Cmp(xprime) != 0) {
+		return false // The commitments sent along must be the ones the proof was made with
+	}
+	cprime := data.proofChallenge(domain, keyID, digest, id, xtilde, xisquared, vprime, xprime)
 	return cprime.Cmp(c) == 0
 }
 
-func (p *ThresholdPlayer) AddShare(msg string, signatureShare *SignatureShare) {
-	if VerifyShare(msg, signatureShare, p.Data) {
-		if len(p.KnownSignatures[msg]) == 0 {
-			p.KnownSignatures[msg] = map[int]*SignatureShare{}
+// AddShare will verify the signature share of msg and remember it if it is valid
+func (p *ThresholdPlayer) AddShare(msg string, signatureShare *SignatureShare) error {
+	if signatureShare == nil {
+		return ErrInvalidShare
+	}
+	params, _ := p.state()
+	if VerifyShare(msg, signatureShare, params) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if len(p.knownSignatures[msg]) == 0 {
+			p.knownSignatures[msg] = map[int]*SignatureShare{}
 		}
-		p.KnownSignatures[msg][signatureShare.id] = signatureShare
-		return
+		p.knownSignatures[msg][signatureShare.id] = signatureShare
+		return nil
 	}
-	fmt.Println("Verification of share failed")
+	return &PlayerError{Err: ErrInvalidShare, PlayerIds: []int{signatureShare.id}}
 }
 
 // CreateSignature will create the signature for the message from k participants signature shares
-func CreateSignature(msg string, data *ThresholdProtocolData, sigShares map[int]*SignatureShare) (*big.Int, bool) {
-	digest := sha256.Sum256([]byte(msg))
-	x := new(big.Int).SetBytes(digest[:])
+func CreateSignature(msg string, data *PublicParams, sigShares map[int]*SignatureShare) (*big.Int, error) {
+	digest, err := data.HashMessage([]byte(msg))
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	return CreateSignatureFromDigest(digest, data, sigShares)
+}
+
+// CreateSignatureFromDigest will create the signature for a message that is already hashed with the message hash
+func CreateSignatureFromDigest(digest []byte, data *PublicParams, sigShares map[int]*SignatureShare) (*big.Int, error) {
+	x, err := data.encodeDigest(digest)
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	return combineSignatureShares(signatureProofDomain, x, data, sigShares)
+}
+
+// CreateSignatureFromEncoded will create the signature from signature shares created by SignEncodedMsg for x, the
+// encoding of msg
+func CreateSignatureFromEncoded(msg string, x *big.Int, data *PublicParams, sigShares map[int]*SignatureShare) (*big.Int, error) {
+	digest, err := data.HashMessage([]byte(msg))
+	if err != nil {
+		return nil, encodingError(err)
+	}
+	return CreateSignatureFromEncodedDigest(digest, x, data, sigShares)
+}
+
+// CreateSignatureFromEncodedDigest is CreateSignatureFromEncoded for a message that is already hashed with the
+// message hash
+func CreateSignatureFromEncodedDigest(digest []byte, x *big.Int, data *PublicParams, sigShares map[int]*SignatureShare) (*big.Int, error) {
+	if err := data.checkEncoding(digest, x); err != nil {
+		return nil, encodingError(err)
+	}
+	return combineSignatureShares(signatureProofDomain, x, data, sigShares)
+}
+
+// combineSignatureShares computes y, such that y^E = x, from k shares of x made in the proof domain
+func combineSignatureShares(domain string, x *big.Int, data *PublicParams, sigShares map[int]*SignatureShare) (*big.Int, error) {
+	if err := data.checkPurpose(domain); err != nil {
+		return nil, err
+	}
 	if len(sigShares) < data.K {
-		fmt.Println("Too few known signature shares")
-		return big.NewInt(0), false
+		return nil, &PlayerError{Err: ErrInsufficientShares, PlayerIds: sortedIds(sigShares)}
+	}
+	invalid := []int{}
+	for id, share := range sigShares {
+		if share.CheckRange(data) != nil || share.id != id {
+			invalid = append(invalid, id)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Ints(invalid)
+		return nil, &PlayerError{Err: ErrInvalidShare, PlayerIds: invalid}
 	}
 	w := big.NewInt(1)
+	// Combine the shares of the k smallest ids, so the same shares always give the same combination
+	ids := sortedIds(sigShares)[:data.K]
 	signatureShares := map[int]*SignatureShare{}
-	count := 0
-	for k, v := range sigShares {
-		if count < data.K {
-			signatureShares[k] = v
-		}
-		count++
+	for _, id := range ids {
+		signatureShares[id] = sigShares[id]
 	}
 
 	for i, v := range signatureShares {
-		// delta_i(0), because we evaluate h(x) at x=0
-		// top/bottom = (0-j)/(i-j)
-		top := One
-		bottom := One
-		for j := range signatureShares {
-			if j != i {
-				top = new(big.Int).Mul(top, big.NewInt(int64(-j)))
-				bottom = new(big.Int).Mul(bottom, big.NewInt(int64(i-j)))
-			}
-		}
-		topBDelta := new(big.Int).Mul(top, data.Delta)
-		lambda := new(big.Int).Div(topBDelta, bottom)
+		lambda := lagrangeCoefficient(data.Delta, i, ids)
 		xipow := new(big.Int).Exp(v.signature, lambda, data.N)
 		w.Mul(w, xipow)
 	}
 	w.Exp(w, Two, data.N)
-	// GCD has set a and b to the correct values we need such that e'a + eb = 1, e' = 4Delta^2
-	wa := new(big.Int).Exp(w, data.GCDa, data.N)
-	xb := new(big.Int).Exp(x, data.GCDb, data.N)
+	// a and b are the values we need such that e'a + eb = 1, e' = 4Delta^2 Scale
+	a, b := data.gcd()
+	wa := new(big.Int).Exp(w, a, data.N)
+	xb := new(big.Int).Exp(x, b, data.N)
 	y := new(big.Int).Mul(wa, xb)
+	y.Mod(y, data.N)
 	ye := new(big.Int).Exp(y, data.E, data.N)
-	return y, ye.Cmp(x) == 0 // At this point x and y^E should be equal
+	if ye.Cmp(x) != 0 { // At this point x and y^E should be equal
+		return nil, &PlayerError{Err: ErrInvalidSignature, PlayerIds: sortedIds(signatureShares)}
+	}
+	return y, nil
+}
+
+// lagrangeCoefficient returns Delta * L_i(0) for the points ids, which is an integer when Delta is divisible by
+// every i - j
+func lagrangeCoefficient(delta *big.Int, i int, ids []int) *big.Int {
+	return lagrangeCoefficientAt(delta, 0, i, ids)
+}
+
+// lagrangeCoefficientAt returns Delta * L_i(x) for the points ids
+func lagrangeCoefficientAt(delta *big.Int, x, i int, ids []int) *big.Int {
+	// top/bottom = prod (x-j)/(i-j)
+	top := new(big.Int).Set(delta)
+	bottom := big.NewInt(1)
+	for _, j := range ids {
+		if j != i {
+			top.Mul(top, big.NewInt(int64(x-j)))
+			bottom.Mul(bottom, big.NewInt(int64(i-j)))
+		}
+	}
+	return top.Div(top, bottom)
+}
+
+// sortedIds returns the player ids of the signature shares in increasing order
+func sortedIds(sigShares map[int]*SignatureShare) []int {
+	ids := make([]int, 0, len(sigShares))
+	for id := range sigShares {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
 }
 
-func VerifySignature(msg string, y *big.Int, data *ThresholdProtocolData) bool {
-	digest := sha256.Sum256([]byte(msg))
-	x := new(big.Int).SetBytes(digest[:])
+func VerifySignature(msg string, y *big.Int, data *PublicParams) bool {
+	digest, err := data.HashMessage([]byte(msg))
+	if err != nil {
+		return false
+	}
+	return VerifyDigestSignature(digest, y, data)
+}
+
+// VerifyDigestSignature verifies the signature of a message that is already hashed with the message hash
+func VerifyDigestSignature(digest []byte, y *big.Int, data *PublicParams) bool {
 	ye := new(big.Int).Exp(y, data.E, data.N)
-	return ye.Cmp(x) == 0
+	return data.checkEncoding(digest, ye) == nil
 }
 
 // Sends the signature share to player
-func SendSignatureShare(msg string, signatureShare *SignatureShare, receiverID int, data *ThresholdProtocolData) {
-	data.Participants[receiverID].OnReceiveSignatureShare(msg, signatureShare)
+func SendSignatureShare(msg string, signatureShare *SignatureShare, receiverID int, data *ThresholdProtocolData) error {
+	return data.Participants[receiverID].OnReceiveSignatureShare(msg, signatureShare)
 }
 
-func (p *ThresholdPlayer) OnReceiveSignatureShare(msg string, signatureShare *SignatureShare) {
-	p.AddShare(msg, signatureShare)
+func (p *ThresholdPlayer) OnReceiveSignatureShare(msg string, signatureShare *SignatureShare) error {
+	return p.AddShare(msg, signatureShare)
 }
 
 // DistributeSignatureShare sends a signature share of message to all players
 // msg is the message that was signed
 // signatureShare is the part of the signature to be sent
-func DistributeSignatureShare(msg string, signatureShare *SignatureShare, data *ThresholdProtocolData) {
+func DistributeSignatureShare(msg string, signatureShare *SignatureShare, data *ThresholdProtocolData) error {
 	for i := 1; i <= data.L; i++ {
-		SendSignatureShare(msg, signatureShare, i, data)
+		if err := SendSignatureShare(msg, signatureShare, i, data); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-// RequestSignatures will request all players 1..l to sign the message msg
+// RequestSignatures will request all players 1..l to sign the message msg. The players sign in parallel, on at
+// most data.Workers goroutines.
 // msg is the message to be signed
-func RequestSignatures(msg string, data *ThresholdProtocolData) []*SignatureShare {
+func RequestSignatures(msg string, data *ThresholdProtocolData) ([]*SignatureShare, error) {
 	signatures := make([]*SignatureShare, data.L)
-	for i := 1; i <= data.L; i++ {
-		signatures[i-1] = data.Participants[i].SignHashOfMsg(msg)
+	err := data.forEach(data.L, func(job int) error {
+		signature, err := data.Participants[job+1].SignHashOfMsg(msg)
+		if err != nil {
+			return &PlayerError{Err: err, PlayerIds: []int{job + 1}}
+		}
+		signatures[job] = signature
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return signatures
+	return signatures, nil
 }
 
-// FullSignAndDistribute will request signatures and distribute them
-func FullSignAndDistribute(msg string, data *ThresholdProtocolData) {
-	signatures := RequestSignatures(msg, data)
-	for _, signature := range signatures {
-		DistributeSignatureShare(msg, signature, data)
+// FullSignAndDistribute will request signatures and distribute them. Every player verifies the signature shares
+// of the others in parallel, like RequestSignatures.
+func FullSignAndDistribute(msg string, data *ThresholdProtocolData) error {
+	signatures, err := RequestSignatures(msg, data)
+	if err != nil {
+		return err
 	}
+	return data.forEach(len(signatures)*data.L, func(job int) error {
+		return SendSignatureShare(msg, signatures[job/data.L], job%data.L+1, data)
+	})
 }
 
-func FullSignAndSendToOne(msg string, data *ThresholdProtocolData, pid int) {
-	signatures := RequestSignatures(msg, data)
-	for _, signature := range signatures {
-		SendSignatureShare(msg, signature, pid, data)
+func FullSignAndSendToOne(msg string, data *ThresholdProtocolData, pid int) error {
+	signatures, err := RequestSignatures(msg, data)
+	if err != nil {
+		return err
+	}
+	return data.forEach(len(signatures), func(job int) error {
+		return SendSignatureShare(msg, signatures[job], pid, data)
+	})
+}
+
+// forEach runs do for the jobs 0..n-1 on a pool of at most data.Workers goroutines. All jobs are run, and the
+// error of the first failed job is returned, so the result does not depend on the order the jobs finish in.
+func (data *ThresholdProtocolData) forEach(n int, do func(job int) error) error {
+	workers := data.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, n)
+	errs := make([]error, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				errs[job] = do(job)
+			}
+		}()
+	}
+	for job := 0; job < n; job++ {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package SecureMPC
+
+import (
+	"crypto"
+	"encoding/binary"
+	"math/big"
+)
+
+// "Transcript" turns an interactive sigma protocol into a non-interactive proof with the Fiat–Shamir transform.
+// Everything the prover sends, and everything the proof is about, is appended to the transcript under a label,
+// and the challenge is a hash of the transcript. Every entry is encoded as
+//
+//	label length  4 bytes
+//	label         bytes
+//	value length  4 bytes
+//	value         bytes
+//
+// so no two different transcripts have the same encoding. A transcript starts with a domain label naming the
+// protocol, such that a proof of one protocol is never accepted by another.
+
+// Transcript is the transcript of a non-interactive proof
+type Transcript struct {
+	hash crypto.Hash
+	data []byte
+}
+
+// NewTranscript starts a transcript of the protocol named by domain. The challenges are computed with hash,
+// which must be available.
+func NewTranscript(hash crypto.Hash, domain string) *Transcript {
+	t := &Transcript{hash: hash}
+	t.AppendBytes("domain", []byte(domain))
+	return t
+}
+
+// AppendBytes appends the value b to the transcript
+func (t *Transcript) AppendBytes(label string, b []byte) {
+	t.data = binary.BigEndian.AppendUint32(t.data, uint32(len(label)))
+	t.data = append(t.data, label...)
+	t.data = binary.BigEndian.AppendUint32(t.data, uint32(len(b)))
+	t.data = append(t.data, b...)
+}
+
+// AppendInt appends the non-negative number x to the transcript
+func (t *Transcript) AppendInt(label string, x int) {
+	t.AppendBytes(label, binary.BigEndian.AppendUint64(nil, uint64(x)))
+}
+
+// AppendBigInt appends x to the transcript. The sign is encoded in the first byte.
+func (t *Transcript) AppendBigInt(label string, x *big.Int) {
+	sign := byte(0)
+	if x.Sign() < 0 {
+		sign = 1
+	}
+	t.AppendBytes(label, append([]byte{sign}, x.Bytes()...))
+}
+
+// Challenge computes a challenge of the given number of bits from the transcript so far, and appends it to the
+// transcript, such that later challenges depend on it. Challenges longer than the hash output are computed by
+// hashing with a counter.
+func (t *Transcript) Challenge(label string, bits int) *big.Int {
+	t.AppendBytes("challenge", []byte(label))
+	size := (bits + 7) / 8
+	var output []byte
+	for counter := uint32(0); len(output) < size; counter++ {
+		h := t.hash.New()
+		h.Write(t.data)
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		output = h.Sum(output)
+	}
+	output = output[:size]
+	c := new(big.Int).SetBytes(output)
+	c.Rsh(c, uint(8*len(output)-bits))
+	t.AppendBytes(label, output)
+	return c
+}
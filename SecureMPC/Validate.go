@@ -0,0 +1,191 @@
+package SecureMPC
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+)
+
+// "Validate" catches threshold setups that can not sign before anybody relies on them. Most broken parameters do
+// not fail loudly: with k > l or k < 1 no set of players combines to a signature, and with e <= l or e dividing
+// 4Delta^2 Scale the combination has no exponents a and b with 4Delta^2 Scale a + e b = 1, so every signature
+// fails to verify. Validate checks what can be checked from the public parameters alone. That V is a square and
+// N a product of safe primes can only be checked knowing the factors of N, so a dealer also runs SelfTest, which
+// signs with every key share and combines the signature shares of random sets of k players.
+
+// selfTestSubsets is the number of random sets of k players the dealer combines a signature of
+const selfTestSubsets = 3
+
+// Validate checks that the public parameters can be used to sign and verify, and returns an ErrInvalidParams
+// naming the first problem otherwise. It can not check that V is a square and N a product of safe primes, but
+// it checks that V and the verification keys have Jacobi symbol 1, as every square does.
+func (params *PublicParams) Validate() error {
+	if err := checkThreshold(params.L, params.K); err != nil {
+		return err
+	}
+	if params.N == nil || params.N.Sign() <= 0 || params.N.Bit(0) == 0 || params.N.BitLen() < 3 {
+		return fmt.Errorf("%w: N must be an odd number larger than 4", ErrInvalidParams)
+	}
+	if err := checkPublicExponent(params.L, params.E); err != nil {
+		return err
+	}
+	if params.Delta == nil || params.Delta.Cmp(new(big.Int).MulRange(1, int64(params.L))) != 0 {
+		return fmt.Errorf("%w: Delta must be l!", ErrInvalidParams)
+	}
+	if params.Scale == nil || params.Scale.Sign() <= 0 {
+		return fmt.Errorf("%w: scale must be positive", ErrInvalidParams)
+	}
+	if new(big.Int).GCD(nil, nil, params.combinationExponent(), params.E).Cmp(One) != 0 {
+		return fmt.Errorf("%w: e must be coprime to 4 Delta^2 Scale", ErrInvalidParams)
+	}
+	if !isQuadraticResidueCandidate(params.V, params.N) {
+		return fmt.Errorf("%w: V is not a square modulo N", ErrInvalidParams)
+	}
+	if len(params.VerificationKeys) != params.L+1 {
+		return fmt.Errorf("%w: there must be a verification key for each of the %d players", ErrInvalidParams, params.L)
+	}
+	invalid := []int{}
+	for i := 1; i <= params.L; i++ {
+		if !isQuadraticResidueCandidate(params.VerificationKeys[i], params.N) {
+			invalid = append(invalid, i)
+		}
+	}
+	if len(invalid) > 0 {
+		return &PlayerError{Err: fmt.Errorf("%w: verification key is not a square modulo N", ErrInvalidParams), PlayerIds: invalid}
+	}
+	if err := checkMessageHash(params.Hash); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	if params.Encoding < EncodingRawHash || params.Encoding > EncodingFDH {
+		return fmt.Errorf("%w: unknown signature encoding %v", ErrInvalidParams, params.Encoding)
+	}
+	if err := params.Proof.Check(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	if params.Epoch < 0 {
+		return fmt.Errorf("%w: epoch must not be negative", ErrInvalidParams)
+	}
+	if params.Purpose != PurposeSigning && params.Purpose != PurposeDecryption {
+		return fmt.Errorf("%w: unknown key purpose %v", ErrInvalidParams, params.Purpose)
+	}
+	return nil
+}
+
+// SelfTest signs a random message with each of the key shares and combines the signature shares of subsets
+// random sets of K players, so a broken deal is noticed before the key shares are handed out. The signature
+// shares are verified as well, and key shares that do not fit their verification keys are named in a
+// PlayerError. The raw hash is signed whatever the encoding and purpose of the parameters, which do not matter
+// for whether the key shares fit together.
+// random is the source of randomness, nil means crypto/rand.Reader
+func SelfTest(random io.Reader, params *PublicParams, keyShares []*KeyShare, subsets int) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if len(keyShares) != params.L+1 {
+		return fmt.Errorf("%w: expected %d key shares, got %d", ErrSelfTest, params.L, len(keyShares)-1)
+	}
+	random = randomOrDefault(random)
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return randomnessError(err)
+	}
+	message := "SecureMPC self test " + hex.EncodeToString(nonce)
+	raw := *params
+	raw.Encoding = EncodingRawHash
+	raw.Purpose = PurposeSigning
+
+	sigShares := make(map[int]*SignatureShare, params.L)
+	invalid := []int{}
+	for i := 1; i <= params.L; i++ {
+		if keyShares[i] == nil || keyShares[i].Id != i {
+			invalid = append(invalid, i)
+			continue
+		}
+		player := NewThresholdPlayer(&raw, keyShares[i])
+		player.Random = random
+		share, err := player.SignHashOfMsg(message)
+		if err != nil {
+			return &PlayerError{Err: fmt.Errorf("%w: %w", ErrSelfTest, err), PlayerIds: []int{i}}
+		}
+		if !VerifyShare(message, share, &raw) {
+			invalid = append(invalid, i)
+			continue
+		}
+		sigShares[i] = share
+	}
+	if len(invalid) > 0 {
+		return &PlayerError{Err: fmt.Errorf("%w: %w", ErrSelfTest, ErrInvalidShare), PlayerIds: invalid}
+	}
+	for s := 0; s < subsets; s++ {
+		ids, err := randomSubset(random, params.L, params.K)
+		if err != nil {
+			return err
+		}
+		subset := make(map[int]*SignatureShare, len(ids))
+		for _, id := range ids {
+			subset[id] = sigShares[id]
+		}
+		sig, err := CreateSignature(message, &raw, subset)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrSelfTest, err)
+		}
+		if !VerifySignature(message, sig, &raw) {
+			return &PlayerError{Err: fmt.Errorf("%w: %w", ErrSelfTest, ErrInvalidSignature), PlayerIds: ids}
+		}
+	}
+	return nil
+}
+
+// checkThreshold checks that k of l players can sign
+func checkThreshold(l, k int) error {
+	if l < 1 {
+		return fmt.Errorf("%w: there must be at least one player, not %d", ErrInvalidParams, l)
+	}
+	if k < 1 || k > l {
+		return fmt.Errorf("%w: the threshold k must be between 1 and l = %d, not %d", ErrInvalidParams, l, k)
+	}
+	return nil
+}
+
+// checkPublicExponent checks that e is a prime larger than l, such that it is coprime to Delta = l!
+func checkPublicExponent(l int, e *big.Int) error {
+	if e == nil || !e.ProbablyPrime(20) {
+		return fmt.Errorf("%w: public exponent %v is not prime", ErrInvalidParams, e)
+	}
+	if e.Cmp(big.NewInt(int64(l))) <= 0 {
+		return fmt.Errorf("%w: public exponent %v must be larger than l = %d", ErrInvalidParams, e, l)
+	}
+	return nil
+}
+
+// isQuadraticResidueCandidate checks that x is in [1,n), coprime to n and has Jacobi symbol 1 modulo n, as every
+// square modulo n that generates the squares is
+func isQuadraticResidueCandidate(x, n *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(n) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, x, n).Cmp(One) == 0 && big.Jacobi(x, n) == 1
+}
+
+// randomSubset returns k distinct ids of 1..l in increasing order, picked uniformly from random
+func randomSubset(random io.Reader, l, k int) ([]int, error) {
+	ids := make([]int, l)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	// A partial Fisher-Yates shuffle puts a uniformly random subset in ids[:k]
+	for i := 0; i < k; i++ {
+		j, err := rand.Int(random, big.NewInt(int64(l-i)))
+		if err != nil {
+			return nil, randomnessError(err)
+		}
+		swap := i + int(j.Int64())
+		ids[i], ids[swap] = ids[swap], ids[i]
+	}
+	subset := ids[:k]
+	sort.Ints(subset)
+	return subset, nil
+}
@@ -0,0 +1,310 @@
+package SecureMPC
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// "WireFormat" is the encoding of signature shares sent between players running in different processes.
+// The binary encoding of a signature share is
+//
+//	version   1 byte
+//	key id    32 bytes
+//	digest    2 byte length + bytes
+//	player id 4 bytes
+//	signature 2 byte length + bytes
+//	c         2 byte length + bytes
+//	z         2 byte length + bytes
+//	v'        2 byte length + bytes
+//	x'        2 byte length + bytes
+//
+// where all lengths and numbers are big endian. Numbers must be minimally encoded, so every share has exactly
+// one encoding. The JSON encoding has the same fields, hex encoded.
+//
+// Version 1 is the same without the commitments v' and x' of the proof, so shares in version 1 can not be batch
+// verified. Both versions are read.
+
+// WireFormatVersion is the version of the signature share encoding written by this package
+const WireFormatVersion = 2
+
+// KeyIDSize is the size of the key id in bytes
+const KeyIDSize = sha256.Size
+
+type signatureShareJSON struct {
+	Version   int    `json:"version"`
+	KeyID     string `json:"keyId"`
+	Digest    string `json:"digest"`
+	Id        int    `json:"id"`
+	Signature string `json:"signature"`
+	C         string `json:"c"`
+	Z         string `json:"z"`
+	VPrime    string `json:"vPrime,omitempty"`
+	XPrime    string `json:"xPrime,omitempty"`
+}
+
+// KeyID identifies the threshold key and the sharing of it, so signature shares can not be mixed up between keys.
+// It is the SHA-256 hash of the public parameters, including the purpose of the key.
+func (params *PublicParams) KeyID() []byte {
+	h := sha256.New()
+	writeLengthPrefixed := func(b []byte) {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(b)))
+		h.Write(b)
+	}
+	writeLengthPrefixed([]byte("SecureMPC threshold RSA key id v1"))
+	_ = binary.Write(h, binary.BigEndian, uint32(params.L))
+	_ = binary.Write(h, binary.BigEndian, uint32(params.K))
+	writeLengthPrefixed(params.N.Bytes())
+	writeLengthPrefixed(params.E.Bytes())
+	writeLengthPrefixed(params.V.Bytes())
+	for i := 1; i <= params.L; i++ {
+		writeLengthPrefixed(params.VerificationKeys[i].Bytes())
+	}
+	// The scale is only part of the id when it is not 1, so the ids of keys sharing d itself stay the same
+	if params.scale().Cmp(One) != 0 {
+		writeLengthPrefixed(params.Scale.Bytes())
+	}
+	// As the scale, the purpose is only part of the id of decryption keys
+	if params.Purpose != PurposeSigning {
+		writeLengthPrefixed([]byte(params.Purpose.String()))
+	}
+	return h.Sum(nil)
+}
+
+// Id returns the id of the player that created the signature share
+func (s *SignatureShare) Id() int {
+	return s.id
+}
+
+// KeyID returns the id of the key the signature share was created with
+func (s *SignatureShare) KeyID() []byte {
+	return s.keyID
+}
+
+// Digest returns the digest of the signed message
+func (s *SignatureShare) Digest() []byte {
+	return s.digest
+}
+
+// MarshalBinary encodes the signature share in the binary wire format
+func (s *SignatureShare) MarshalBinary() ([]byte, error) {
+	if len(s.keyID) != KeyIDSize {
+		return nil, errors.New("signature share has no key id")
+	}
+	numbers := []*big.Int{s.signature, s.c, s.z, s.vprime, s.xprime}
+	version := byte(WireFormatVersion)
+	if s.vprime == nil {
+		numbers = numbers[:3]
+		version = 1
+	}
+	b := []byte{version}
+	b = append(b, s.keyID...)
+	var err error
+	if b, err = appendLengthPrefixed(b, s.digest); err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(s.id))
+	for _, x := range numbers {
+		if b, err = appendLengthPrefixed(b, x.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a signature share in the binary wire format. It only checks the encoding itself, use
+// DecodeSignatureShare to also check the values against the public parameters.
+func (s *SignatureShare) UnmarshalBinary(data []byte) error {
+	if len(data) < 1+KeyIDSize {
+		return errors.New("signature share is too short")
+	}
+	if data[0] != 1 && data[0] != WireFormatVersion {
+		return fmt.Errorf("unsupported signature share version %d", data[0])
+	}
+	keyID := append([]byte(nil), data[1:1+KeyIDSize]...)
+	rest := data[1+KeyIDSize:]
+	digest, rest, err := readLengthPrefixed(rest)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 4 {
+		return errors.New("signature share is too short")
+	}
+	id := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	numbers := make([]*big.Int, 5)
+	if data[0] == 1 {
+		numbers = numbers[:3]
+	}
+	for i := range numbers {
+		var number []byte
+		if number, rest, err = readLengthPrefixed(rest); err != nil {
+			return err
+		}
+		if numbers[i], err = decodeMinimalBigInt(number); err != nil {
+			return err
+		}
+	}
+	if len(rest) != 0 {
+		return errors.New("trailing data after signature share")
+	}
+	return s.set(keyID, digest, int64(id), numbers)
+}
+
+// MarshalJSON encodes the signature share as JSON
+func (s *SignatureShare) MarshalJSON() ([]byte, error) {
+	encoded := &signatureShareJSON{
+		Version:   1,
+		KeyID:     hex.EncodeToString(s.keyID),
+		Digest:    hex.EncodeToString(s.digest),
+		Id:        s.id,
+		Signature: hex.EncodeToString(s.signature.Bytes()),
+		C:         hex.EncodeToString(s.c.Bytes()),
+		Z:         hex.EncodeToString(s.z.Bytes()),
+	}
+	if s.vprime != nil {
+		encoded.Version = WireFormatVersion
+		encoded.VPrime = hex.EncodeToString(s.vprime.Bytes())
+		encoded.XPrime = hex.EncodeToString(s.xprime.Bytes())
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a signature share encoded by MarshalJSON, with the same checks as UnmarshalBinary
+func (s *SignatureShare) UnmarshalJSON(data []byte) error {
+	var encoded signatureShareJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	hexFields := []string{encoded.KeyID, encoded.Digest, encoded.Signature, encoded.C, encoded.Z}
+	switch encoded.Version {
+	case 1:
+		if encoded.VPrime != "" || encoded.XPrime != "" {
+			return errors.New("version 1 signature shares have no commitments")
+		}
+	case WireFormatVersion:
+		hexFields = append(hexFields, encoded.VPrime, encoded.XPrime)
+	default:
+		return fmt.Errorf("unsupported signature share version %d", encoded.Version)
+	}
+	fields := make([][]byte, len(hexFields))
+	for i, field := range hexFields {
+		var err error
+		if fields[i], err = hex.DecodeString(field); err != nil {
+			return err
+		}
+	}
+	if len(fields[0]) != KeyIDSize {
+		return errors.New("invalid key id size")
+	}
+	numbers := make([]*big.Int, len(fields)-2)
+	for i := range numbers {
+		var err error
+		if numbers[i], err = decodeMinimalBigInt(fields[i+2]); err != nil {
+			return err
+		}
+	}
+	return s.set(fields[0], fields[1], int64(encoded.Id), numbers)
+}
+
+// DecodeSignatureShare decodes a signature share in the binary wire format and rejects it, if it does not belong
+// to the key or if any of its values are out of range. Only shares passing this should be given to VerifyShare.
+func DecodeSignatureShare(data []byte, params *PublicParams) (*SignatureShare, error) {
+	s := &SignatureShare{}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if err := s.CheckRange(params); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CheckRange checks that the signature share belongs to the key and that its values are in range. The signature
+// must be invertible modulo N, as verifying and combining raise it to negative powers.
+func (s *SignatureShare) CheckRange(params *PublicParams) error {
+	if s == nil || s.signature == nil || s.c == nil || s.z == nil {
+		return errors.New("signature share is incomplete")
+	}
+	if string(s.keyID) != string(params.KeyID()) {
+		return errors.New("signature share belongs to a different key")
+	}
+	if s.id < 1 || s.id > params.L {
+		return fmt.Errorf("player id %d is not in range [1,%d]", s.id, params.L)
+	}
+	if checkMessageHash(params.Hash) != nil || len(s.digest) != params.Hash.Size() {
+		return errors.New("invalid digest size")
+	}
+	if s.signature.Sign() <= 0 || s.signature.Cmp(params.N) >= 0 {
+		return errors.New("signature is not in range [1,N-1]")
+	}
+	if new(big.Int).GCD(nil, nil, s.signature, params.N).Cmp(One) != 0 {
+		return errors.New("signature is not coprime to N")
+	}
+	if s.c.BitLen() > params.Proof.HashBits {
+		return errors.New("challenge c is larger than the hash output")
+	}
+	if !params.Proof.zInRange(s.z) {
+		return errors.New("response z is out of range of the proof parameters")
+	}
+	if s.vprime != nil && (s.vprime.Sign() <= 0 || s.vprime.Cmp(params.N) >= 0 || s.xprime.Sign() <= 0 ||
+		s.xprime.Cmp(params.N) >= 0) {
+		return errors.New("commitments are not in range [1,N-1]")
+	}
+	return nil
+}
+
+// set fills in the decoded signature share after the checks that need no public parameters. numbers are the
+// signature, c, z and, from version 2 on, v' and x'.
+func (s *SignatureShare) set(keyID, digest []byte, id int64, numbers []*big.Int) error {
+	if len(digest) == 0 {
+		return errors.New("signature share has no digest")
+	}
+	if id < 1 || id > 1<<31-1 {
+		return fmt.Errorf("invalid player id %d", id)
+	}
+	*s = SignatureShare{
+		signature: numbers[0],
+		c:         numbers[1],
+		z:         numbers[2],
+		id:        int(id),
+		keyID:     keyID,
+		digest:    digest,
+	}
+	if len(numbers) == 5 {
+		s.vprime = numbers[3]
+		s.xprime = numbers[4]
+	}
+	return nil
+}
+
+func appendLengthPrefixed(b, field []byte) ([]byte, error) {
+	if len(field) > 1<<16-1 {
+		return nil, errors.New("field is too long for the wire format")
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(field)))
+	return append(b, field...), nil
+}
+
+func readLengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errors.New("signature share is too short")
+	}
+	length := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+length {
+		return nil, nil, errors.New("signature share is too short")
+	}
+	return append([]byte(nil), data[2:2+length]...), data[2+length:], nil
+}
+
+// decodeMinimalBigInt decodes a big endian number, which must not have leading zero bytes
+func decodeMinimalBigInt(b []byte) (*big.Int, error) {
+	if len(b) > 0 && b[0] == 0 {
+		return nil, errors.New("number is not minimally encoded")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
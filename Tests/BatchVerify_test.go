@@ -0,0 +1,66 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBatchVerifyAcrossMessages(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(5, 3, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []SecureMPC.BatchItem{}
+	for _, message := range []string{"first", "second", "third"} {
+		shares, err := SecureMPC.RequestSignatures(message, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, share := range shares {
+			items = append(items, SecureMPC.BatchItem{Msg: message, Share: share})
+		}
+	}
+	if invalid := SecureMPC.BatchVerifyShares(items, data.PublicParams); len(invalid) != 0 {
+		t.Errorf("Expected all shares to be valid, got invalid %v", invalid)
+	}
+	// A share claimed to be for another message is found
+	items[4].Msg = "third"
+	if invalid := SecureMPC.BatchVerifyShares(items, data.PublicParams); !reflect.DeepEqual(invalid, []int{4}) {
+		t.Errorf("Expected item 4 to be invalid, got %v", invalid)
+	}
+}
+
+func TestBatchVerifyFindsForgedProof(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(5, 3, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Hi hello"
+	if err := SecureMPC.FullSignAndSendToOne(message, data, 1); err != nil {
+		t.Fatal(err)
+	}
+	sigmap := data.Participants[1].KnownShares(message)
+	if invalid := SecureMPC.BatchVerifySharesOfMsg(message, sigmap, data.PublicParams); len(invalid) != 0 {
+		t.Errorf("Expected all shares to be valid, got invalid %v", invalid)
+	}
+	// Player 3 sends the proof response z of player 2
+	var forged, other map[string]interface{}
+	encoded, _ := json.Marshal(sigmap[3])
+	_ = json.Unmarshal(encoded, &forged)
+	encoded, _ = json.Marshal(sigmap[2])
+	_ = json.Unmarshal(encoded, &other)
+	forged["z"] = other["z"]
+	encoded, _ = json.Marshal(forged)
+	forgedShare := &SecureMPC.SignatureShare{}
+	if err := json.Unmarshal(encoded, forgedShare); err != nil {
+		t.Fatal(err)
+	}
+	shares := map[int]*SecureMPC.SignatureShare{1: sigmap[1], 2: sigmap[2], 3: forgedShare, 4: sigmap[4]}
+	if invalid := SecureMPC.BatchVerifySharesOfMsg(message, shares, data.PublicParams); !reflect.DeepEqual(invalid, []int{3}) {
+		t.Errorf("Expected player 3 to be invalid, got %v", invalid)
+	}
+}
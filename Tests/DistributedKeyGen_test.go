@@ -0,0 +1,137 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// distributedKeyGeneration runs the distributed key generation between l players in one process, delivering the
+// messages of every round to their receivers. tamper, if not nil, may change the messages before they are
+// delivered.
+func distributedKeyGeneration(l, k, keysize int, tamper func([]*SecureMPC.KeyGenMessage)) (*SecureMPC.ThresholdProtocolData, error) {
+	players := make([]*SecureMPC.KeyGenPlayer, l+1)
+	messages := []*SecureMPC.KeyGenMessage{}
+	for i := 1; i <= l; i++ {
+		var err error
+		if players[i], err = SecureMPC.NewKeyGenPlayer(nil, i, l, k, keysize); err != nil {
+			return nil, err
+		}
+		sent, err := players[i].Start()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, sent...)
+	}
+	for !players[1].Done() {
+		if tamper != nil {
+			tamper(messages)
+		}
+		next := []*SecureMPC.KeyGenMessage{}
+		for i := 1; i <= l; i++ {
+			received := []*SecureMPC.KeyGenMessage{}
+			for _, m := range messages {
+				if m.To == 0 || m.To == i {
+					received = append(received, m)
+				}
+			}
+			sent, err := players[i].Step(received)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, sent...)
+		}
+		messages = next
+	}
+	keyShares := make([]*SecureMPC.KeyShare, l+1)
+	var params *SecureMPC.PublicParams
+	for i := 1; i <= l; i++ {
+		playerParams, keyShare, err := players[i].Result()
+		if err != nil {
+			return nil, err
+		}
+		if params != nil && string(params.KeyID()) != string(playerParams.KeyID()) {
+			return nil, errors.New("players ended up with different public parameters")
+		}
+		params = playerParams
+		keyShares[i] = keyShare
+	}
+	return SecureMPC.NewThresholdProtocolData(params, keyShares), nil
+}
+
+func TestDistributedThresholdProtocol(t *testing.T) {
+	message := "Hi hello"
+	data, err := distributedKeyGeneration(5, 3, 512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.N.BitLen() != 512 {
+		t.Errorf("Expected modulus of 512 bits, got %d", data.N.BitLen())
+	}
+	for i := 1; i <= data.L; i++ {
+		if new(big.Int).Mod(data.Participants[i].KeyShare().Secret(), big.NewInt(int64(i))).Sign() != 0 {
+			t.Errorf("Key share of player %d is not a multiple of %d", i, i)
+		}
+	}
+	if err := SecureMPC.FullSignAndDistribute(message, data); err != nil {
+		t.Fatal(err)
+	}
+	sigmap := data.Participants[2].KnownShares(message)
+	if len(sigmap) != 5 {
+		t.Errorf("Expected 5 verified signature shares, got %d", len(sigmap))
+	}
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+		t.Errorf("Verification failed")
+	}
+}
+
+func TestDistributedThresholdProtocolEveryPlayerSet(t *testing.T) {
+	message := "Every set of k players"
+	data, err := distributedKeyGeneration(4, 2, 512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatures, err := SecureMPC.RequestSignatures(message, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			sigmap := map[int]*SecureMPC.SignatureShare{i + 1: signatures[i], j + 1: signatures[j]}
+			sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+			if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+				t.Errorf("Verification failed for players %d and %d", i+1, j+1)
+			}
+		}
+	}
+}
+
+func TestDistributedKeyGenerationRefusesInvalidParams(t *testing.T) {
+	for _, lkSize := range [][3]int{{2, 1, 512}, {2, 2, 512}, {1, 1, 512}, {3, 0, 512}, {3, 4, 512}, {3, 2, 256}, {3, 2, 513}, {3, 1, 512}, {4, 3, 512}} {
+		_, err := SecureMPC.NewKeyGenPlayer(nil, 1, lkSize[0], lkSize[1], lkSize[2])
+		if !errors.Is(err, SecureMPC.ErrInvalidParams) {
+			t.Errorf("Expected %d of %d players with %d bits to be refused, got %v", lkSize[1], lkSize[0], lkSize[2], err)
+		}
+	}
+}
+
+func TestDistributedKeyGenerationNamesInvalidMessages(t *testing.T) {
+	if _, err := SecureMPC.NewKeyGenPlayer(nil, 4, 3, 2, 512); !errors.Is(err, SecureMPC.ErrInvalidParams) {
+		t.Errorf("Expected player 4 of 3 to be refused, got %v", err)
+	}
+	// Player 2 sends its share of N for the wrong round
+	_, err := distributedKeyGeneration(3, 2, 512, func(messages []*SecureMPC.KeyGenMessage) {
+		for _, m := range messages {
+			if m.From == 2 && m.To == 0 {
+				m.Round++
+			}
+		}
+	})
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrInvalidKeyGen) || !errors.As(err, &playerErr) || !reflect.DeepEqual(playerErr.PlayerIds, []int{2}) {
+		t.Errorf("Expected an invalid message of player 2, got %v", err)
+	}
+}
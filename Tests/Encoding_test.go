@@ -0,0 +1,155 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPKCS1v15SignatureVerifiesWithCryptoRSA(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingPKCS1v15
+	publicKey := &rsa.PublicKey{N: params.N, E: int(params.E.Int64())}
+	for _, message := range []string{"Hi hello", "", "A standard RSA signature"} {
+		sigmap := map[int]*SecureMPC.SignatureShare{}
+		for _, id := range []int{1, 4, 5} {
+			sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+		}
+		sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+		if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+			t.Fatalf("Verification failed")
+		}
+		digest := sha256.Sum256([]byte(message))
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], SecureMPC.SignatureBytes(sig, params)); err != nil {
+			t.Errorf("crypto/rsa rejected the signature of %q: %v", message, err)
+		}
+	}
+}
+
+func TestRawHashSignatureIsNotPKCS1v15(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Hi hello"
+	if err := SecureMPC.FullSignAndSendToOne(message, data, 1); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, data.Participants[1].KnownShares(message))
+	if err != nil {
+		t.Fatalf("Verification failed: %v", err)
+	}
+	publicKey := &rsa.PublicKey{N: data.N, E: int(data.E.Int64())}
+	digest := sha256.Sum256([]byte(message))
+	if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], SecureMPC.SignatureBytes(sig, data.PublicParams)) == nil {
+		t.Errorf("Expected raw hash signature to be rejected by crypto/rsa")
+	}
+}
+
+func TestPSSSignatureVerifiesWithCryptoRSA(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingPSS
+	publicKey := &rsa.PublicKey{N: params.N, E: int(params.E.Int64())}
+	message := "Downstream only accepts PSS"
+	if _, err := SecureMPC.NewThresholdPlayer(params, keyShares[1]).SignHashOfMsg(message); !errors.Is(err, SecureMPC.ErrEncoding) {
+		t.Errorf("Expected a player to refuse encoding a PSS message by itself")
+	}
+	// The coordinator picks the salt and encodes the message once
+	x, err := SecureMPC.EncodePSS(nil, message, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{2, 3, 5} {
+		share, err := SecureMPC.NewThresholdPlayer(params, keyShares[id]).SignEncodedMsg(message, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !SecureMPC.VerifyEncodedShare(message, x, share, params) {
+			t.Errorf("Share of player %d did not verify", id)
+		}
+		sigmap[id] = share
+	}
+	sig, err := SecureMPC.CreateSignatureFromEncoded(message, x, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Fatalf("Verification failed")
+	}
+	digest := sha256.Sum256([]byte(message))
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+	if err := rsa.VerifyPSS(publicKey, crypto.SHA256, digest[:], SecureMPC.SignatureBytes(sig, params), opts); err != nil {
+		t.Errorf("crypto/rsa rejected the signature: %v", err)
+	}
+	if SecureMPC.VerifySignature("Another message", sig, params) {
+		t.Errorf("Signature verified for another message")
+	}
+}
+
+func TestPlayersRefuseWrongPSSEncoding(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingPSS
+	x, err := SecureMPC.EncodePSS(nil, "The message the coordinator claims", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := SecureMPC.NewThresholdPlayer(params, keyShares[1])
+	if _, err := player.SignEncodedMsg("Another message", x); !errors.Is(err, SecureMPC.ErrEncoding) {
+		t.Errorf("Expected the player to refuse signing an encoding of another message")
+	}
+	if _, err := player.SignEncodedMsg("The message the coordinator claims", new(big.Int).Add(x, big.NewInt(1))); err == nil {
+		t.Errorf("Expected the player to refuse signing an invalid encoding")
+	}
+}
+
+func TestFDHSignaturesAreUnique(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingFDH
+	message := "Seed for round 17"
+	sign := func(ids ...int) *big.Int {
+		sigmap := map[int]*SecureMPC.SignatureShare{}
+		for _, id := range ids {
+			sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+		}
+		sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+		if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+			t.Fatalf("Verification failed")
+		}
+		return sig
+	}
+	first := sign(1, 2, 3)
+	if first.Cmp(sign(2, 4, 5)) != 0 {
+		t.Errorf("Expected every set of k players to create the same signature")
+	}
+	// The expanded value uses the whole modulus instead of the 256 bits of the digest
+	x := new(big.Int).Exp(first, params.E, params.N)
+	if x.BitLen() < params.N.BitLen()-64 {
+		t.Errorf("Expected a full domain hash, got %d bits", x.BitLen())
+	}
+	output, err := SecureMPC.FDHOutput(first, params)
+	if err != nil || len(output) != sha256.Size {
+		t.Fatalf("Expected an output of %d bytes, got %d: %v", sha256.Size, len(output), err)
+	}
+	if SecureMPC.VerifySignature("Seed for round 18", first, params) {
+		t.Errorf("Signature verified for another message")
+	}
+}
@@ -0,0 +1,38 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestErrorsNamePlayers(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 4, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Hi hello"
+	player := SecureMPC.NewThresholdPlayer(params, keyShares[1])
+	other := signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[3]), "Something else")
+	err = player.AddShare(message, other)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrInvalidShare) || !errors.As(err, &playerErr) || !reflect.DeepEqual(playerErr.PlayerIds, []int{3}) {
+		t.Errorf("Expected invalid share of player 3, got %v", err)
+	}
+
+	sigmap := map[int]*SecureMPC.SignatureShare{
+		1: signShare(t, player, message),
+		2: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), message),
+	}
+	_, err = SecureMPC.CreateSignature(message, params, sigmap)
+	if !errors.Is(err, SecureMPC.ErrInsufficientShares) || !errors.As(err, &playerErr) || !reflect.DeepEqual(playerErr.PlayerIds, []int{1, 2}) {
+		t.Errorf("Expected too few shares from players 1 and 2, got %v", err)
+	}
+	// CreateSignature does not verify the shares, so a share of another message only shows in the signature
+	sigmap[3] = other
+	if _, err := SecureMPC.CreateSignature(message, params, sigmap); !errors.Is(err, SecureMPC.ErrInvalidSignature) {
+		t.Errorf("Expected an invalid signature, got %v", err)
+	}
+}
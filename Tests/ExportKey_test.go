@@ -0,0 +1,150 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestExportPublicKey(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 1024, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingPKCS1v15
+	message := "Verified elsewhere"
+	sigmap := map[int]*SecureMPC.SignatureShare{
+		2: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), message),
+		3: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[3]), message),
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := SecureMPC.EncodePublicKeyPEM(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != SecureMPC.PKIXPEMType {
+		t.Fatalf("Expected a PEM block of type %q", SecureMPC.PKIXPEMType)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok || key.N.Cmp(params.N) != 0 || int64(key.E) != params.E.Int64() {
+		t.Fatalf("Exported key differs from the threshold key")
+	}
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig.FillBytes(make([]byte, key.Size()))); err != nil {
+		t.Errorf("Threshold signature does not verify with the exported key: %v", err)
+	}
+}
+
+func TestEncodeAuthorizedKey(t *testing.T) {
+	params, _, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := SecureMPC.EncodeAuthorizedKey(params, "threshold@example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) != 3 || fields[0] != "ssh-rsa" || fields[2] != "threshold@example" {
+		t.Fatalf("Unexpected authorized key line %q", line)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts [][]byte
+	for len(blob) > 0 {
+		if len(blob) < 4 || int(binary.BigEndian.Uint32(blob)) > len(blob)-4 {
+			t.Fatalf("Truncated SSH key blob")
+		}
+		n := binary.BigEndian.Uint32(blob)
+		parts = append(parts, blob[4:4+n])
+		blob = blob[4+n:]
+	}
+	if len(parts) != 3 || string(parts[0]) != "ssh-rsa" {
+		t.Fatalf("Expected the key type, e and n in the blob")
+	}
+	// N has its top bit set, so its mpint has a leading zero byte
+	if parts[2][0] != 0 || new(big.Int).SetBytes(parts[2]).Cmp(params.N) != 0 ||
+		!bytes.Equal(parts[1], []byte{0x01, 0x00, 0x01}) {
+		t.Errorf("Blob does not encode the threshold key")
+	}
+}
+
+func TestEncodeJWK(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingPSS
+	data, err := SecureMPC.EncodeJWK(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key map[string]string
+	if err := json.Unmarshal(data, &key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(key["n"])
+	if err != nil || new(big.Int).SetBytes(n).Cmp(params.N) != 0 || key["e"] != "AQAB" {
+		t.Errorf("JWK does not encode the threshold key")
+	}
+	if key["kty"] != "RSA" || key["use"] != "sig" || key["alg"] != "PS256" {
+		t.Errorf("Unexpected JWK %s", data)
+	}
+	decryption := *params
+	decryption.Purpose = SecureMPC.PurposeDecryption
+	if data, err := SecureMPC.EncodeJWK(&decryption); err != nil || !strings.Contains(string(data), `"use":"enc"`) ||
+		strings.Contains(string(data), `"alg"`) {
+		t.Errorf("Expected a decryption key to be marked for encryption, got %s", data)
+	}
+	kid, err := SecureMPC.JWKThumbprint(params)
+	if err != nil || key["kid"] != kid {
+		t.Errorf("Expected the key id to be the thumbprint")
+	}
+
+	// The key id stays the same when the key shares are refreshed, unlike KeyID
+	protocol := SecureMPC.NewThresholdProtocolData(params, keyShares)
+	if err := SecureMPC.RefreshShares(protocol); err != nil {
+		t.Fatal(err)
+	}
+	refreshedKid, err := SecureMPC.JWKThumbprint(protocol.PublicParams)
+	if err != nil || refreshedKid != kid || bytes.Equal(protocol.PublicParams.KeyID(), params.KeyID()) {
+		t.Errorf("Expected a refresh to keep the thumbprint and change the key id")
+	}
+}
+
+func TestJWKThumbprintKnownAnswer(t *testing.T) {
+	// The example of RFC 7638 section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &SecureMPC.PublicParams{N: new(big.Int).SetBytes(n), E: big.NewInt(65537)}
+	kid, err := SecureMPC.JWKThumbprint(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Unexpected thumbprint %s", kid)
+	}
+}
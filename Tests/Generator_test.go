@@ -8,13 +8,19 @@ import (
 )
 
 func TestPrimeGen(t *testing.T) {
-	n, m := SecureMPC.GeneratePrimes(18)
+	n, m, err := SecureMPC.GeneratePrimes(nil, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
 	fmt.Println(n)
 	fmt.Println(m)
 }
 
 func TestRSAGen(t *testing.T) {
-	n, e, d, m := SecureMPC.GenerateRSAKey(512)
+	n, e, d, m, err := SecureMPC.GenerateRSAKey(nil, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
 	fmt.Println(n)
 	fmt.Println(e)
 	fmt.Println(d)
@@ -0,0 +1,118 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// safePrimeRSAKey makes a crypto/rsa key of 2*bits bits with safe primes and public exponent e
+func safePrimeRSAKey(t *testing.T, bits, e int) *rsa.PrivateKey {
+	t.Helper()
+	var primes []*big.Int
+	for len(primes) < 2 {
+		p, _, err := SecureMPC.GenerateSafePrime(context.Background(), nil, bits, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(primes) == 0 || p.Cmp(primes[0]) != 0 {
+			primes = append(primes, p)
+		}
+	}
+	pMinus1 := new(big.Int).Sub(primes[0], big.NewInt(1))
+	qMinus1 := new(big.Int).Sub(primes[1], big.NewInt(1))
+	lambda := new(big.Int).Div(new(big.Int).Mul(pMinus1, qMinus1), new(big.Int).GCD(nil, nil, pMinus1, qMinus1))
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(primes[0], primes[1]), E: e},
+		D:         new(big.Int).ModInverse(big.NewInt(int64(e)), lambda),
+		Primes:    primes,
+	}
+	if key.D == nil {
+		t.Fatalf("%d is not invertible", e)
+	}
+	key.Precompute()
+	return key
+}
+
+func TestDealFromRSAKey(t *testing.T) {
+	key := safePrimeRSAKey(t, 512, 65537)
+	params, keyShares, err := SecureMPC.DealFromRSAKey(nil, 5, 3, key, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.N.Cmp(key.N) != 0 || params.E.Int64() != int64(key.E) {
+		t.Fatalf("Imported key has another public key")
+	}
+	params.Encoding = SecureMPC.EncodingPKCS1v15
+	message := "Signed by the imported key"
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{1, 4, 5} {
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig.FillBytes(make([]byte, key.Size()))); err != nil {
+		t.Errorf("Threshold signature does not verify with crypto/rsa: %v", err)
+	}
+}
+
+func TestDealFromPKCS8PEM(t *testing.T) {
+	key := safePrimeRSAKey(t, 512, 65537)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: SecureMPC.PKCS8PEMType, Bytes: der})
+	params, keyShares, err := SecureMPC.DealFromPKCS8PEM(nil, 3, 2, data, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Signed from PEM"
+	sigmap := map[int]*SecureMPC.SignatureShare{
+		1: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[1]), message),
+		3: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[3]), message),
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Signature of the imported key does not verify")
+	}
+
+	wrongType := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if _, _, err := SecureMPC.DealFromPKCS8PEM(nil, 3, 2, wrongType, SecureMPC.PurposeSigning); err == nil {
+		t.Errorf("Expected a PKCS #1 PEM block to be refused")
+	}
+}
+
+func TestDealFromRSAKeyRefusesUnsuitableKeys(t *testing.T) {
+	plain, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SecureMPC.DealFromRSAKey(nil, 3, 2, plain, SecureMPC.PurposeSigning); !errors.Is(err, SecureMPC.ErrUnsuitableKey) {
+		t.Errorf("Expected a key without safe primes to be refused, got %v", err)
+	}
+
+	small := safePrimeRSAKey(t, 512, 3)
+	if _, _, err := SecureMPC.DealFromRSAKey(nil, 5, 3, small, SecureMPC.PurposeSigning); !errors.Is(err, SecureMPC.ErrUnsuitableKey) {
+		t.Errorf("Expected a public exponent not larger than l to be refused, got %v", err)
+	}
+	if _, _, err := SecureMPC.DealFromRSAKey(nil, 2, 2, small, SecureMPC.PurposeSigning); err != nil {
+		t.Errorf("Expected a public exponent larger than l to be accepted, got %v", err)
+	}
+
+	composite := safePrimeRSAKey(t, 512, 9)
+	if _, _, err := SecureMPC.DealFromRSAKey(nil, 3, 2, composite, SecureMPC.PurposeSigning); !errors.Is(err, SecureMPC.ErrUnsuitableKey) {
+		t.Errorf("Expected a composite public exponent to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+var testKeyOnce sync.Once
+var testN, testE, testD, testM *big.Int
+
+// testKey returns a 1024 bit RSA key shared by the tests, since generating safe primes takes a while.
+// crypto/rsa refuses keys shorter than 1024 bits.
+func testKey() (*big.Int, *big.Int, *big.Int, *big.Int) {
+	testKeyOnce.Do(func() {
+		var err error
+		testN, testE, testD, testM, err = SecureMPC.GenerateRSAKey(nil, 1024)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return testN, testE, testD, testM
+}
+
+// signShare lets the player sign msg and fails the test if that is not possible
+func signShare(t *testing.T, player *SecureMPC.ThresholdPlayer, msg string) *SecureMPC.SignatureShare {
+	t.Helper()
+	share, err := player.SignHashOfMsg(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return share
+}
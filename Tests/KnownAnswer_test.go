@@ -0,0 +1,263 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"SecureMPC/internal/testhooks"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// The known answer vectors in testdata/threshold_rsa_vectors.json fix the output of SignHashOfMsg,
+// CreateSignature and VerifyShare, so any change of behavior is noticed, and let other implementations check
+// themselves against this one. Each vector has
+//
+//	params     the public parameters, as written by PublicParams.MarshalJSON
+//	keyShares  the secret key share of each player, hex encoded
+//	messages   for each message its digest, the signature share of each player in the JSON wire format together
+//	           with the randomness r of its proof, the signers whose shares are combined and the signature
+//
+// A signature share is fully determined by the key share, the message and r, where z = s_i*c + r.
+// Run go test -run TestKnownAnswerVectors -update to regenerate the vectors after an intended change.
+
+var updateVectors = flag.Bool("update", false, "regenerate the known answer vectors in testdata")
+
+const vectorsFile = "testdata/threshold_rsa_vectors.json"
+
+type vectorFile struct {
+	Version     int          `json:"version"`
+	Description string       `json:"description"`
+	Vectors     []testVector `json:"vectors"`
+}
+
+type testVector struct {
+	Name      string            `json:"name"`
+	Params    json.RawMessage   `json:"params"`
+	KeyShares map[string]string `json:"keyShares"` // KeyShares maps player ids to key shares
+	Messages  []vectorMessage   `json:"messages"`
+}
+
+type vectorMessage struct {
+	Message   string        `json:"message"`
+	Digest    string        `json:"digest"`
+	Shares    []vectorShare `json:"shares"`
+	Signers   []int         `json:"signers"`
+	Signature string        `json:"signature"`
+}
+
+type vectorShare struct {
+	Id    int             `json:"id"`
+	R     string          `json:"r"`
+	Share json.RawMessage `json:"share"`
+}
+
+// vectorConfigs are the keys the vectors are generated for
+var vectorConfigs = []struct {
+	name     string
+	l, k     int
+	hash     crypto.Hash
+	encoding SecureMPC.SignatureEncoding
+}{
+	{"3 of 5, SHA-256, raw hash", 5, 3, crypto.SHA256, SecureMPC.EncodingRawHash},
+	{"2 of 3, SHA-256, PKCS #1 v1.5", 3, 2, crypto.SHA256, SecureMPC.EncodingPKCS1v15},
+	{"2 of 4, SHA3-256, full domain hash", 4, 2, crypto.SHA3_256, SecureMPC.EncodingFDH},
+}
+
+var vectorMessages = []string{"", "Hi hello", "The quick brown fox jumps over the lazy dog"}
+
+func TestKnownAnswerVectors(t *testing.T) {
+	if *updateVectors {
+		writeVectors(t)
+	}
+	data, err := os.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file vectorFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != 1 || len(file.Vectors) == 0 {
+		t.Fatalf("Unexpected vector file version %d with %d vectors", file.Version, len(file.Vectors))
+	}
+	for _, vector := range file.Vectors {
+		t.Run(vector.Name, func(t *testing.T) {
+			runVector(t, vector)
+		})
+	}
+}
+
+func runVector(t *testing.T, vector testVector) {
+	params := &SecureMPC.PublicParams{}
+	if err := json.Unmarshal(vector.Params, params); err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range vector.Messages {
+		digest, err := params.HashMessage([]byte(message.Message))
+		if err != nil || hex.EncodeToString(digest) != message.Digest {
+			t.Fatalf("Digest of %q is %x, expected %s", message.Message, digest, message.Digest)
+		}
+		sigShares := map[int]*SecureMPC.SignatureShare{}
+		for _, expected := range message.Shares {
+			keyShare := vectorKeyShare(t, vector, expected.Id, params.Epoch)
+			share, err := signWithR(params, keyShare, message.Message, parseHex(t, expected.R))
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := json.Marshal(share)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, encoded, expected.Share) {
+				t.Errorf("Signature share of player %d for %q differs from the vector", expected.Id, message.Message)
+			}
+			decoded := &SecureMPC.SignatureShare{}
+			if err := json.Unmarshal(expected.Share, decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !SecureMPC.VerifyShare(message.Message, decoded, params) {
+				t.Errorf("Signature share of player %d for %q does not verify", expected.Id, message.Message)
+			}
+			if SecureMPC.VerifyShare(message.Message+".", decoded, params) {
+				t.Errorf("Signature share of player %d verifies for another message", expected.Id)
+			}
+			sigShares[expected.Id] = decoded
+		}
+		signers := map[int]*SecureMPC.SignatureShare{}
+		for _, id := range message.Signers {
+			signers[id] = sigShares[id]
+		}
+		sig, err := SecureMPC.CreateSignature(message.Message, params, signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Text(16) != message.Signature {
+			t.Errorf("Signature of %q differs from the vector", message.Message)
+		}
+		if !SecureMPC.VerifySignature(message.Message, parseHex(t, message.Signature), params) {
+			t.Errorf("Signature of %q does not verify", message.Message)
+		}
+	}
+}
+
+// signWithR signs msg with the key share, using r as the randomness of the proof
+func signWithR(params *SecureMPC.PublicParams, keyShare *SecureMPC.KeyShare, msg string, r *big.Int) (*SecureMPC.SignatureShare, error) {
+	return SecureMPC.NewThresholdPlayer(params, keyShare).SignHashOfMsgWithR(testhooks.Key{}, msg, r)
+}
+
+func vectorKeyShare(t *testing.T, vector testVector, id, epoch int) *SecureMPC.KeyShare {
+	t.Helper()
+	secret, ok := vector.KeyShares[strconv.Itoa(id)]
+	if !ok {
+		t.Fatalf("Vector has no key share of player %d", id)
+	}
+	return SecureMPC.NewKeyShare(id, epoch, parseHex(t, secret))
+}
+
+func parseHex(t *testing.T, s string) *big.Int {
+	t.Helper()
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("Invalid hex number %q", s)
+	}
+	return x
+}
+
+// jsonEqual compares two JSON encodings independently of their formatting
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y interface{}
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := json.Marshal(x)
+	cb, _ := json.Marshal(y)
+	return bytes.Equal(ca, cb)
+}
+
+// writeVectors generates the vectors from seeded randomness and writes them to testdata
+func writeVectors(t *testing.T) {
+	file := vectorFile{
+		Version:     1,
+		Description: "Known answer vectors of Shoup threshold RSA signatures, see KnownAnswer_test.go",
+	}
+	for i, config := range vectorConfigs {
+		random := seededReader(byte(100 + i))
+		params, keyShares, err := SecureMPC.Deal(random, config.l, config.k, 512, SecureMPC.PurposeSigning)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.Hash = config.hash
+		params.Encoding = config.encoding
+		encodedParams, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vector := testVector{Name: config.name, Params: encodedParams, KeyShares: map[string]string{}}
+		for id := 1; id <= config.l; id++ {
+			vector.KeyShares[strconv.Itoa(id)] = keyShares[id].Secret().Text(16)
+		}
+		bound := new(big.Int).Lsh(big.NewInt(1), uint(params.Proof.ShareBits+params.Proof.HashBits+params.Proof.Slack))
+		for j, msg := range vectorMessages {
+			digest, err := params.HashMessage([]byte(msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			message := vectorMessage{Message: msg, Digest: hex.EncodeToString(digest)}
+			sigShares := map[int]*SecureMPC.SignatureShare{}
+			for id := 1; id <= config.l; id++ {
+				r, err := rand.Int(random, bound)
+				if err != nil {
+					t.Fatal(err)
+				}
+				share, err := signWithR(params, keyShares[id], msg, r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				encoded, err := json.Marshal(share)
+				if err != nil {
+					t.Fatal(err)
+				}
+				message.Shares = append(message.Shares, vectorShare{Id: id, R: r.Text(16), Share: encoded})
+				sigShares[id] = share
+			}
+			// Different signers for each message, which must all give the same signature
+			signers := map[int]*SecureMPC.SignatureShare{}
+			for s := 0; s < config.k; s++ {
+				id := (j+s)%config.l + 1
+				message.Signers = append(message.Signers, id)
+				signers[id] = sigShares[id]
+			}
+			sig, err := SecureMPC.CreateSignature(msg, params, signers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			message.Signature = sig.Text(16)
+			vector.Messages = append(vector.Messages, message)
+		}
+		file.Vectors = append(file.Vectors, vector)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vectorsFile, append(data, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,34 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// poolEnv names a directory of pooled safe primes for the 512 bit keys of the tests. With it, the tests draw
+// their keys from the pool, which is refilled in the background for the next run.
+const poolEnv = "SECUREMPC_PRIME_POOL"
+
+func TestMain(m *testing.M) {
+	dir := os.Getenv(poolEnv)
+	if dir == "" {
+		os.Exit(m.Run())
+	}
+	pool, err := SecureMPC.OpenSafePrimePool(dir, 256)
+	if err == nil {
+		err = SecureMPC.UseSafePrimePool(pool)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Using the safe prime pool failed:", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := pool.FillInBackground(ctx, 200, &SecureMPC.SafePrimeOptions{Workers: 1})
+	code := m.Run()
+	cancel()
+	<-done
+	os.Exit(code)
+}
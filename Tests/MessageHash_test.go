@@ -0,0 +1,120 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMessageHashesVerifyWithCryptoRSA(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := &rsa.PublicKey{N: params.N, E: int(params.E.Int64())}
+	// A message which is only ever read as a stream
+	message := func() io.Reader {
+		return io.LimitReader(strings.NewReader(strings.Repeat("large artifact ", 1<<16)), 1<<20)
+	}
+	for _, hash := range SecureMPC.MessageHashes {
+		params.Hash = hash
+		digest, err := params.HashReader(message())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, encoding := range []SecureMPC.SignatureEncoding{SecureMPC.EncodingPKCS1v15, SecureMPC.EncodingPSS} {
+			params.Encoding = encoding
+			sigmap := map[int]*SecureMPC.SignatureShare{}
+			var sig []byte
+			if encoding == SecureMPC.EncodingPSS {
+				x, err := SecureMPC.EncodePSSDigest(nil, digest, params)
+				if 2*hash.Size()+2 > (params.N.BitLen()+6)/8 {
+					// A 1024 bit modulus is too short for PSS with SHA-512 and a salt as long as the hash
+					if err == nil {
+						t.Errorf("Expected PSS with %v to be refused for a %d bit modulus", hash, params.N.BitLen())
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, id := range []int{1, 3} {
+					if sigmap[id], err = SecureMPC.NewThresholdPlayer(params, keyShares[id]).SignEncodedDigest(digest, x); err != nil {
+						t.Fatal(err)
+					}
+				}
+				y, err := SecureMPC.CreateSignatureFromEncodedDigest(digest, x, params, sigmap)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sig = SecureMPC.SignatureBytes(y, params)
+				err = rsa.VerifyPSS(publicKey, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+				if err != nil {
+					t.Errorf("crypto/rsa rejected the PSS signature with %v: %v", hash, err)
+				}
+			} else {
+				for _, id := range []int{1, 3} {
+					if sigmap[id], err = SecureMPC.NewThresholdPlayer(params, keyShares[id]).SignReader(message()); err != nil {
+						t.Fatal(err)
+					}
+					if !SecureMPC.VerifyDigestShare(digest, sigmap[id], params) {
+						t.Errorf("Share of player %d did not verify with %v", id, hash)
+					}
+				}
+				y, err := SecureMPC.CreateSignatureFromDigest(digest, params, sigmap)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sig = SecureMPC.SignatureBytes(y, params)
+				if err := rsa.VerifyPKCS1v15(publicKey, hash, digest, sig); err != nil {
+					t.Errorf("crypto/rsa rejected the PKCS #1 v1.5 signature with %v: %v", hash, err)
+				}
+				if !SecureMPC.VerifyDigestSignature(digest, y, params) {
+					t.Errorf("Signature with %v did not verify", hash)
+				}
+			}
+		}
+	}
+}
+
+func TestMessageHashIsStoredAndChecked(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Hash = crypto.SHA3_256
+	stored, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &SecureMPC.PublicParams{}
+	if err := json.Unmarshal(stored, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Hash != crypto.SHA3_256 {
+		t.Errorf("Expected SHA3-256 to be stored, got %v", loaded.Hash)
+	}
+	message := []byte("Hi hello")
+	digest, _ := loaded.HashMessage(message)
+	share, err := SecureMPC.NewThresholdPlayer(loaded, keyShares[2]).SignDigest(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !SecureMPC.VerifyShare(string(message), share, loaded) || !bytes.Equal(share.Digest(), digest) {
+		t.Errorf("Share of the pre-hashed message did not verify")
+	}
+	if _, err := SecureMPC.NewThresholdPlayer(loaded, keyShares[2]).SignDigest(digest[:20]); err == nil {
+		t.Errorf("Expected a digest of the wrong length to be refused")
+	}
+	params.Hash = crypto.MD5
+	if _, err := params.HashMessage(message); err == nil {
+		t.Errorf("Expected MD5 to be refused")
+	}
+}
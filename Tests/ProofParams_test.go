@@ -0,0 +1,77 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestProofParamsFollowModulus(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key shares are dealt over the integers, so they are longer than N by the statistical slack and more
+	if params.Proof.ShareBits <= n.BitLen()+128 || params.Proof.HashBits != 256 || params.Proof.Slack != SecureMPC.DefaultProofSlack {
+		t.Errorf("Unexpected default proof parameters %+v", params.Proof)
+	}
+	message := "Hi hello"
+	share := signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[1]), message)
+	// A z beyond the bound can not come from an honest player
+	var encoded map[string]interface{}
+	data, _ := json.Marshal(share)
+	_ = json.Unmarshal(data, &encoded)
+	z, _ := new(big.Int).SetString(encoded["z"].(string), 16)
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(params.Proof.ShareBits+params.Proof.HashBits+params.Proof.Slack+1))
+	encoded["z"] = hex.EncodeToString(z.Add(z, bound).Bytes())
+	data, _ = json.Marshal(encoded)
+	large := &SecureMPC.SignatureShare{}
+	if err := json.Unmarshal(data, large); err != nil {
+		t.Fatal(err)
+	}
+	if large.CheckRange(params) == nil || SecureMPC.VerifyShare(message, large, params) {
+		t.Errorf("Expected a share with z out of range to be rejected")
+	}
+}
+
+func TestConfiguredProofParams(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Proof.Slack = 80
+	params.Proof.HashBits = 160
+	message := "Shorter proofs"
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{1, 3} {
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+		if !SecureMPC.VerifyShare(message, sigmap[id], params) || sigmap[id].CheckRange(params) != nil {
+			t.Errorf("Share of player %d did not verify", id)
+		}
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Verification failed")
+	}
+	// The proof parameters are part of the stored public parameters
+	stored, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &SecureMPC.PublicParams{}
+	if err := json.Unmarshal(stored, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Proof != params.Proof {
+		t.Errorf("Expected proof parameters %+v, got %+v", params.Proof, loaded.Proof)
+	}
+	// A share larger than ShareBits can not be proven
+	params.Proof.ShareBits = 16
+	if _, err := SecureMPC.NewThresholdPlayer(params, keyShares[1]).SignHashOfMsg(message); err == nil {
+		t.Errorf("Expected signing with a key share larger than ShareBits to fail")
+	}
+}
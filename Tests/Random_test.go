@@ -0,0 +1,102 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	mrand "math/rand/v2"
+	"testing"
+)
+
+// seededReader returns a deterministic stream of randomness, which must only be used in tests
+func seededReader(seed byte) io.Reader {
+	return mrand.NewChaCha8([32]byte{seed})
+}
+
+func TestSeededDealIsReproducible(t *testing.T) {
+	message := "Reproducible"
+	var shares [2][]byte
+	var keyIDs [2][]byte
+	for run := 0; run < 2; run++ {
+		params, keyShares, err := SecureMPC.Deal(seededReader(1), 3, 2, 512, SecureMPC.PurposeSigning)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyIDs[run] = params.KeyID()
+		player := SecureMPC.NewThresholdPlayer(params, keyShares[2])
+		player.Random = seededReader(2)
+		share := signShare(t, player, message)
+		if shares[run], err = json.Marshal(share); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(keyIDs[0], keyIDs[1]) {
+		t.Errorf("Deals from the same seed differ")
+	}
+	if !bytes.Equal(shares[0], shares[1]) {
+		t.Errorf("Signature shares from the same seed differ")
+	}
+
+	params, _, err := SecureMPC.Deal(seededReader(3), 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(params.KeyID(), keyIDs[0]) {
+		t.Errorf("Deals from different seeds are equal")
+	}
+}
+
+func TestSeededPrimesAndSharesAreReproducible(t *testing.T) {
+	n1, m1, err := SecureMPC.GeneratePrimes(seededReader(4), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, m2, err := SecureMPC.GeneratePrimes(seededReader(4), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n1.Cmp(n2) != 0 || m1.Cmp(m2) != 0 || n1.BitLen() != 256 {
+		t.Errorf("Expected the same 256 bit modulus from the same seed")
+	}
+
+	var shares [2]map[int]int
+	for run := 0; run < 2; run++ {
+		protocol := SecureMPC.MakeProtocolData(29, 20, 10)
+		protocol.SetRandom(seededReader(5))
+		player := protocol.GetPlayer(1)
+		player.AssignSecret(5)
+		if err := player.CreateShares(*protocol); err != nil {
+			t.Fatal(err)
+		}
+		shares[run] = player.GetMap()
+	}
+	for i, share := range shares[0] {
+		if shares[1][i] != share {
+			t.Fatalf("Secret shares from the same seed differ")
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no entropy")
+}
+
+func TestFailingRandomness(t *testing.T) {
+	if _, _, err := SecureMPC.Deal(failingReader{}, 3, 2, 512, SecureMPC.PurposeSigning); !errors.Is(err, SecureMPC.ErrRandomness) {
+		t.Errorf("Expected ErrRandomness from Deal, got %v", err)
+	}
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := SecureMPC.NewThresholdPlayer(params, keyShares[1])
+	player.Random = failingReader{}
+	if _, err := player.SignHashOfMsg("Hi hello"); !errors.Is(err, SecureMPC.ErrRandomness) {
+		t.Errorf("Expected ErrRandomness from signing, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRecoverLostShare(t *testing.T) {
+	message := "Signed with a recovered share"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(5, 3, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyShare, err := SecureMPC.RecoverShare(data, 2, []int{1, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyShare.Id != 2 {
+		t.Fatalf("Recovered key share of player %d", keyShare.Id)
+	}
+	recovered := SecureMPC.NewThresholdPlayer(data.PublicParams, keyShare)
+	sigmap := map[int]*SecureMPC.SignatureShare{
+		2: signShare(t, recovered, message),
+		3: signShare(t, data.Participants[3], message),
+		5: signShare(t, data.Participants[5], message),
+	}
+	if !SecureMPC.VerifyShare(message, sigmap[2], data.PublicParams) {
+		t.Errorf("Signature share of the recovered key share does not verify")
+	}
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+		t.Errorf("Verification failed: %v", err)
+	}
+}
+
+func TestRecoverAfterRefreshAndDistributedKey(t *testing.T) {
+	message := "Recovered in a later epoch"
+	data, err := distributedKeyGeneration(4, 2, 512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SecureMPC.RefreshShares(data); err != nil {
+		t.Fatal(err)
+	}
+	keyShare, err := SecureMPC.RecoverShare(data, 4, []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{
+		1: signShare(t, data.Participants[1], message),
+		4: signShare(t, SecureMPC.NewThresholdPlayer(data.PublicParams, keyShare), message),
+	}
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+		t.Errorf("Verification failed: %v", err)
+	}
+}
+
+func TestRecoveryDetectsInvalidShares(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(4, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SecureMPC.RecoverShare(data, 1, []int{2}); !errors.Is(err, SecureMPC.ErrInvalidRecovery) {
+		t.Errorf("Recovery with too few helpers was accepted: %v", err)
+	}
+	if _, err := SecureMPC.RecoverShare(data, 1, []int{1, 2}); !errors.Is(err, SecureMPC.ErrInvalidRecovery) {
+		t.Errorf("Recovery helped by the lost player was accepted: %v", err)
+	}
+
+	helpers := []int{2, 3, 4}
+	masks := make([]*SecureMPC.RecoveryMasks, len(helpers))
+	for i, id := range helpers {
+		if masks[i], err = data.Participants[id].DealRecoveryMasks(1, helpers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Helper 3 gets a wrong mask from helper 4
+	received := map[int]*big.Int{2: masks[0].MaskFor(3), 4: new(big.Int).Add(masks[2].MaskFor(3), big.NewInt(1))}
+	_, err = data.Participants[3].CreateRecoveryShare(masks, received)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrInvalidRecovery) || !errors.As(err, &playerErr) ||
+		len(playerErr.PlayerIds) != 1 || playerErr.PlayerIds[0] != 4 {
+		t.Errorf("Expected ErrInvalidRecovery blaming player 4, got %v", err)
+	}
+
+	// Helper 2 sends a wrong recovery share to the lost player
+	shares := make([]*SecureMPC.RecoveryShare, len(helpers))
+	for i, id := range helpers {
+		received := map[int]*big.Int{}
+		for _, m := range masks {
+			if m.Dealer != id {
+				received[m.Dealer] = m.MaskFor(id)
+			}
+		}
+		if shares[i], err = data.Participants[id].CreateRecoveryShare(masks, received); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shares[0].Value.Add(shares[0].Value, big.NewInt(1))
+	_, err = SecureMPC.RecoverKeyShare(data.PublicParams, masks, shares)
+	if !errors.Is(err, SecureMPC.ErrInvalidRecovery) || !errors.As(err, &playerErr) ||
+		len(playerErr.PlayerIds) != 1 || playerErr.PlayerIds[0] != 2 {
+		t.Errorf("Expected ErrInvalidRecovery blaming player 2, got %v", err)
+	}
+}
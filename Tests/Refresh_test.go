@@ -0,0 +1,224 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRefreshSharesKeepsKey(t *testing.T) {
+	message := "Signed across epochs"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(5, 3, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for epoch := 1; epoch <= 2; epoch++ {
+		if err := SecureMPC.RefreshShares(data); err != nil {
+			t.Fatal(err)
+		}
+		if data.PublicParams.Epoch != epoch {
+			t.Fatalf("Expected epoch %d, got %d", epoch, data.PublicParams.Epoch)
+		}
+		for _, ids := range [][]int{{1, 2, 3}, {2, 4, 5}, {1, 3, 5}} {
+			sigmap := map[int]*SecureMPC.SignatureShare{}
+			for _, id := range ids {
+				sigmap[id] = signShare(t, data.Participants[id], message)
+			}
+			sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+			if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+				t.Fatalf("Verification failed for players %v in epoch %d: %v", ids, epoch, err)
+			}
+		}
+	}
+	if data.PublicParams.N.Cmp(n) != 0 || data.PublicParams.E.Cmp(e) != 0 {
+		t.Errorf("Refresh changed the public key")
+	}
+}
+
+func TestRefreshRejectsOldEpoch(t *testing.T) {
+	message := "Stolen before the refresh"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldParams := data.PublicParams
+	oldKeyShare := data.Participants[1].KeyShare()
+	oldShare := signShare(t, data.Participants[1], message)
+	if err := SecureMPC.RefreshShares(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if SecureMPC.VerifyShare(message, oldShare, data.PublicParams) {
+		t.Errorf("Signature share of the previous epoch was accepted")
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{1: oldShare, 2: signShare(t, data.Participants[2], message)}
+	if _, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap); err == nil {
+		t.Errorf("Signature shares of different epochs were combined")
+	}
+
+	// A key share of the previous epoch can not sign under the new parameters
+	stale := SecureMPC.NewThresholdPlayer(data.PublicParams, oldKeyShare)
+	if _, err := stale.SignHashOfMsg(message); !errors.Is(err, SecureMPC.ErrStaleKeyShare) {
+		t.Errorf("Expected ErrStaleKeyShare, got %v", err)
+	}
+	// and a refreshed key share can not sign under the old parameters
+	current := SecureMPC.NewThresholdPlayer(oldParams, data.Participants[1].KeyShare())
+	if _, err := current.SignHashOfMsg(message); !errors.Is(err, SecureMPC.ErrStaleKeyShare) {
+		t.Errorf("Expected ErrStaleKeyShare, got %v", err)
+	}
+}
+
+func TestRefreshDetectsInvalidShare(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deals := make([]*SecureMPC.RefreshDeal, 3)
+	for i := 1; i <= 3; i++ {
+		if deals[i-1], err = data.Participants[i].DealRefresh(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refreshed, err := SecureMPC.RefreshedParams(data.PublicParams, deals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares := map[int]*big.Int{}
+	for _, deal := range deals {
+		shares[deal.Dealer] = deal.ShareFor(2)
+	}
+	shares[3] = new(big.Int).Add(shares[3], big.NewInt(1))
+	err = data.Participants[2].ApplyRefresh(refreshed, deals, shares)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrInvalidRefresh) || !errors.As(err, &playerErr) ||
+		len(playerErr.PlayerIds) != 1 || playerErr.PlayerIds[0] != 3 {
+		t.Fatalf("Expected ErrInvalidRefresh blaming player 3, got %v", err)
+	}
+	if data.Participants[2].KeyShare().Epoch != 0 {
+		t.Errorf("Key share changed although the refresh failed")
+	}
+	if _, err := SecureMPC.RefreshedParams(data.PublicParams, deals[:2]); !errors.Is(err, SecureMPC.ErrInvalidRefresh) {
+		t.Errorf("Refresh without every player was accepted: %v", err)
+	}
+}
+
+func TestRefreshRejectsOversizedShare(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := data.PublicParams
+	// A dealer committing to a huge coefficient a sends player 2 the value 2a, which matches the commitment
+	for _, c := range []struct {
+		coef *big.Int
+		ok   bool
+	}{{big.NewInt(5), true}, {new(big.Int).Lsh(big.NewInt(1), uint(params.Proof.ShareBits+256)), false}} {
+		deal := &SecureMPC.RefreshDeal{
+			Dealer:      1,
+			Epoch:       params.Epoch,
+			Commitments: []*big.Int{new(big.Int).Exp(params.V, c.coef, params.N)},
+		}
+		share := new(big.Int).Lsh(c.coef, 1)
+		if SecureMPC.VerifyRefreshShare(deal, 2, share, params) != c.ok {
+			t.Errorf("Expected a value of %d bits to verify: %v", share.BitLen(), c.ok)
+		}
+	}
+}
+
+func TestRefreshKeepsShareBitsBounded(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SecureMPC.RefreshShares(data); err != nil {
+		t.Fatal(err)
+	}
+	first := data.PublicParams.Proof.ShareBits
+	for epoch := 2; epoch <= 4; epoch++ {
+		if err := SecureMPC.RefreshShares(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The refreshes add values of the same size every epoch, rather than values larger than the key shares by the
+	// statistical slack, so each of them adds at most one bit
+	if grown := data.PublicParams.Proof.ShareBits - first; grown > 3 {
+		t.Errorf("Share bits grew by %d bits in three refreshes", grown)
+	}
+}
+
+func TestFailedRefreshKeepsEveryPlayerInTheEpoch(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(4, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Player 3 deals like everybody else, but its key share does not fit its verification key
+	wrong := new(big.Int).Add(data.Participants[3].KeyShare().Secret(), big.NewInt(1))
+	data.Participants[3] = SecureMPC.NewThresholdPlayer(data.PublicParams, SecureMPC.NewKeyShare(3, 0, wrong))
+	if err := SecureMPC.RefreshShares(data); err == nil {
+		t.Fatalf("Expected the refresh to fail")
+	}
+	if data.PublicParams.Epoch != 0 {
+		t.Errorf("Expected the public parameters to stay in epoch 0, got %d", data.PublicParams.Epoch)
+	}
+	for id := 1; id <= 4; id++ {
+		if epoch := data.Participants[id].KeyShare().Epoch; epoch != 0 {
+			t.Errorf("Expected player %d to stay in epoch 0, got %d", id, epoch)
+		}
+	}
+	message := "Signed after the failed refresh"
+	sigmap := map[int]*SecureMPC.SignatureShare{
+		1: signShare(t, data.Participants[1], message),
+		4: signShare(t, data.Participants[4], message),
+	}
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+		t.Errorf("Verification failed after the failed refresh: %v", err)
+	}
+}
+
+func TestStoreRefreshedKeyShare(t *testing.T) {
+	message := "Stored after the refresh"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SecureMPC.RefreshShares(data); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := SecureMPC.EncodePublicParamsPEM(data.PublicParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := SecureMPC.DecodePublicParams(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Epoch != 1 || params.Proof != data.PublicParams.Proof {
+		t.Fatalf("Stored public parameters lost the epoch or proof parameters")
+	}
+	passphrase := []byte("refreshed")
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{1, 3} {
+		sealed, err := SecureMPC.SealKeyShare(data.Participants[id].KeyShare(), passphrase, data.PublicParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyShare, err := SecureMPC.OpenKeyShare(sealed, passphrase, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShare), message)
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Verification failed: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// signWith lets the players with the given ids sign message and combines their signature shares
+func signWith(t *testing.T, data *SecureMPC.ThresholdProtocolData, ids []int, message string) *big.Int {
+	t.Helper()
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range ids {
+		sigmap[id] = signShare(t, data.Participants[id], message)
+	}
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+	if err != nil {
+		t.Fatalf("Combining the shares of players %v failed: %v", ids, err)
+	}
+	return sig
+}
+
+func TestReshareToNewCommittee(t *testing.T) {
+	message := "Signed by the next committee"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(5, 3, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reshared, err := SecureMPC.Reshare(data, []int{1, 3, 5}, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reshared.L != 4 || reshared.K != 2 || reshared.Delta.Cmp(big.NewInt(24)) != 0 {
+		t.Fatalf("Reshared key has l = %d, k = %d, Delta = %v", reshared.L, reshared.K, reshared.Delta)
+	}
+	if reshared.N.Cmp(n) != 0 || reshared.E.Cmp(e) != 0 {
+		t.Fatalf("Resharing changed the public key")
+	}
+	for _, ids := range [][]int{{1, 2}, {3, 4}, {1, 4}, {2, 3, 4}} {
+		sig := signWith(t, reshared, ids, message)
+		if !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+			t.Errorf("Signature of players %v does not verify under the old key", ids)
+		}
+	}
+
+	// Resharing again, with a larger committee than needed, and refreshing the result keeps the key
+	again, err := SecureMPC.Reshare(reshared, []int{1, 2, 4}, 6, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 1; j <= again.L; j++ {
+		if new(big.Int).Mod(again.Participants[j].KeyShare().Secret(), big.NewInt(int64(j))).Sign() != 0 {
+			t.Errorf("Reshared key share of player %d is not a multiple of %d", j, j)
+		}
+	}
+	if err := SecureMPC.RefreshShares(again); err != nil {
+		t.Fatal(err)
+	}
+	sig := signWith(t, again, []int{2, 3, 5, 6}, message)
+	if !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+		t.Errorf("Signature of the twice reshared key does not verify")
+	}
+}
+
+func TestReshareToThresholdOne(t *testing.T) {
+	message := "Signed by any single player"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reshared, err := SecureMPC.Reshare(data, []int{1, 3}, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{1, 2} {
+		sig := signWith(t, reshared, []int{id}, message)
+		if !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+			t.Errorf("Signature of player %d alone does not verify", id)
+		}
+	}
+}
+
+func TestReshareRefusesOldCommittee(t *testing.T) {
+	message := "Signed by the previous committee"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SecureMPC.Reshare(data, []int{2}, 3, 2); !errors.Is(err, SecureMPC.ErrInvalidReshare) {
+		t.Errorf("Resharing with too small a committee was accepted: %v", err)
+	}
+	if _, err := SecureMPC.Reshare(data, []int{1, 2}, 3, 4); !errors.Is(err, SecureMPC.ErrInvalidReshare) {
+		t.Errorf("Resharing to k > l was accepted: %v", err)
+	}
+	if _, err := SecureMPC.Reshare(data, []int{1, 2}, 3, 0); !errors.Is(err, SecureMPC.ErrInvalidReshare) {
+		t.Errorf("Resharing to k = 0 was accepted: %v", err)
+	}
+	reshared, err := SecureMPC.Reshare(data, []int{1, 2}, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := SecureMPC.NewThresholdPlayer(reshared.PublicParams, data.Participants[1].KeyShare())
+	if _, err := old.SignHashOfMsg(message); !errors.Is(err, SecureMPC.ErrStaleKeyShare) {
+		t.Errorf("Expected ErrStaleKeyShare, got %v", err)
+	}
+}
+
+func TestReshareDetectsInvalidDeal(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	committee := []int{1, 3}
+	deals := make([]*SecureMPC.ReshareDeal, len(committee))
+	for i, id := range committee {
+		if deals[i], err = data.Participants[id].DealReshare(committee, 4, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reshared, err := SecureMPC.ResharedParams(data.PublicParams, deals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares := map[int]*big.Int{1: deals[0].ShareFor(4), 3: new(big.Int).Add(deals[1].ShareFor(4), big.NewInt(1))}
+	_, err = SecureMPC.ReshareKeyShare(data.PublicParams, reshared, deals, 4, shares)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrInvalidReshare) || !errors.As(err, &playerErr) ||
+		len(playerErr.PlayerIds) != 1 || playerErr.PlayerIds[0] != 3 {
+		t.Errorf("Expected ErrInvalidReshare blaming player 3, got %v", err)
+	}
+
+	// A dealer sharing something else than its key share is caught by the verification keys
+	deals[1].Commitments[0] = new(big.Int).Exp(data.V, big.NewInt(42), data.N)
+	_, err = SecureMPC.ResharedParams(data.PublicParams, deals)
+	if !errors.Is(err, SecureMPC.ErrInvalidReshare) || !errors.As(err, &playerErr) || playerErr.PlayerIds[0] != 3 {
+		t.Errorf("Expected ErrInvalidReshare blaming player 3, got %v", err)
+	}
+}
+
+func TestStoreResharedParams(t *testing.T) {
+	message := "Stored after resharing"
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reshared, err := SecureMPC.Reshare(data, []int{2, 3}, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := SecureMPC.EncodePublicParamsPEM(reshared.PublicParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := SecureMPC.DecodePublicParams(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Scale.Cmp(reshared.Scale) != 0 || params.Epoch != reshared.Epoch ||
+		string(params.KeyID()) != string(reshared.KeyID()) {
+		t.Fatalf("Stored public parameters differ from the reshared ones")
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{1, 2, 4} {
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, reshared.Participants[id].KeyShare()), message)
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Verification failed: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestRobustCombinerRejectsCheaters(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Hi hello"
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for id := 1; id <= 5; id++ {
+		player := SecureMPC.NewThresholdPlayer(params, keyShares[id])
+		if id == 2 || id == 4 {
+			// Players 2 and 4 cheat by sending shares of another message
+			sigmap[id] = signShare(t, player, "Something else")
+		} else {
+			sigmap[id] = signShare(t, player, message)
+		}
+	}
+	result, err := SecureMPC.CreateSignatureRobust(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, result.Signature, params) {
+		t.Fatalf("Verification failed")
+	}
+	if !reflect.DeepEqual(result.Used, []int{1, 3, 5}) {
+		t.Errorf("Expected players 1, 3 and 5 to be used, got %v", result.Used)
+	}
+	if !reflect.DeepEqual(result.Rejected, []int{2, 4}) {
+		t.Errorf("Expected players 2 and 4 to be rejected, got %v", result.Rejected)
+	}
+}
+
+func TestRobustCombinerTooFewValidShares(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 4, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Hi hello"
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for id := 1; id <= 4; id++ {
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+	}
+	// Player 3 resends the share of player 1 as its own
+	sigmap[3] = sigmap[1]
+	sigmap[4] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[4]), "Something else")
+	result, err := SecureMPC.CreateSignatureRobust(message, params, sigmap)
+	if !errors.Is(err, SecureMPC.ErrInsufficientShares) || result.Signature != nil {
+		t.Errorf("Expected combination of 2 valid shares to fail for k = 3")
+	}
+	if !reflect.DeepEqual(result.Rejected, []int{3, 4}) {
+		t.Errorf("Expected players 3 and 4 to be rejected, got %v", result.Rejected)
+	}
+}
+
+func TestRobustCombinerGivesUpOnInvalidSignature(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shares verify, but the public exponent is not the one d belongs to, so none of the 10 subsets combines
+	params.E = big.NewInt(65539)
+	message := "Hi hello"
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for id := 1; id <= 5; id++ {
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+	}
+	result, err := SecureMPC.CreateSignatureRobust(message, params, sigmap)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrInvalidSignature) || !errors.As(err, &playerErr) || result.Signature != nil {
+		t.Fatalf("Expected an invalid signature from valid shares, got %v", err)
+	}
+	if !reflect.DeepEqual(playerErr.PlayerIds, []int{1, 2, 3}) || len(result.Rejected) != 0 {
+		t.Errorf("Expected the first 3 players to be named and none rejected, got %v and %v", playerErr.PlayerIds, result.Rejected)
+	}
+}
@@ -0,0 +1,145 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func filledPool(t *testing.T, bits, count int) *SecureMPC.SafePrimePool {
+	t.Helper()
+	pool, err := SecureMPC.OpenSafePrimePool(t.TempDir(), bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-pool.FillInBackground(context.Background(), count, nil); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestSafePrimePoolTakesEachPrimeOnce(t *testing.T) {
+	pool := filledPool(t, 64, 3)
+	if n, err := pool.Len(); err != nil || n != 3 {
+		t.Fatalf("Expected 3 pooled primes, got %d (%v)", n, err)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		p, pprime, err := pool.Take()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BitLen() != 64 || !pprime.ProbablyPrime(20) || new(big.Int).Rsh(p, 1).Cmp(pprime) != 0 || seen[p.String()] {
+			t.Errorf("Unexpected pooled prime %v", p)
+		}
+		seen[p.String()] = true
+	}
+	if _, _, err := pool.Take(); !errors.Is(err, SecureMPC.ErrSafePrimePoolEmpty) {
+		t.Errorf("Expected the pool to be empty, got %v", err)
+	}
+	if entries, _ := os.ReadDir(pool.Dir); len(entries) != 0 {
+		t.Errorf("Expected taken primes to be deleted, found %d files", len(entries))
+	}
+}
+
+func TestSafePrimePoolConcurrentTakes(t *testing.T) {
+	pool := filledPool(t, 32, 4)
+	var mu sync.Mutex
+	taken := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, _, err := pool.Take()
+			if errors.Is(err, SecureMPC.ErrSafePrimePoolEmpty) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			taken[p.String()]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(taken) != 4 {
+		t.Errorf("Expected 4 distinct primes to be taken, got %d", len(taken))
+	}
+	for p, n := range taken {
+		if n != 1 {
+			t.Errorf("Prime %s was taken %d times", p, n)
+		}
+	}
+}
+
+func TestSafePrimePoolRefusesInvalidPrimes(t *testing.T) {
+	if _, err := SecureMPC.OpenSafePrimePool(t.TempDir(), 5); err == nil {
+		t.Errorf("Expected a pool of 5 bit safe primes to be refused")
+	}
+	pool, err := SecureMPC.OpenSafePrimePool(t.TempDir(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 65521 is prime, but 32760 is not
+	if err := os.WriteFile(filepath.Join(pool.Dir, "16-bad.prime"), []byte("fff1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pool.Take(); err == nil || errors.Is(err, SecureMPC.ErrSafePrimePoolEmpty) {
+		t.Errorf("Expected an invalid pooled prime to be refused, got %v", err)
+	}
+	if n, _ := pool.Len(); n != 0 {
+		t.Errorf("Expected the invalid prime to be removed")
+	}
+}
+
+func TestKeyGenerationDrawsFromPool(t *testing.T) {
+	pool := filledPool(t, 128, 2)
+	// A seeded reader must not draw from the pool
+	if _, _, err := SecureMPC.GeneratePrimesContext(context.Background(), seededReader(7), 256,
+		&SecureMPC.SafePrimeOptions{Pool: pool}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := pool.Len(); n != 2 {
+		t.Fatalf("Expected key generation from a seeded reader to leave the pool alone, %d primes left", n)
+	}
+
+	if err := SecureMPC.UseSafePrimePool(pool); err != nil {
+		t.Fatal(err)
+	}
+	defer SecureMPC.UseSafePrimePool(nil)
+	n, _, _, m, err := SecureMPC.GenerateRSAKey(nil, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := pool.Len(); left != 0 || n.BitLen() != 256 || m.BitLen() < 252 {
+		t.Errorf("Expected both primes to be drawn from the pool, %d primes left", left)
+	}
+	// The empty pool falls back to fresh primes
+	if _, _, _, _, err := SecureMPC.GenerateRSAKey(nil, 256); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefusePooledPrimes(t *testing.T) {
+	pool := filledPool(t, 128, 2)
+	SecureMPC.RefusePooledPrimes(true)
+	defer SecureMPC.RefusePooledPrimes(false)
+	if err := SecureMPC.UseSafePrimePool(pool); !errors.Is(err, SecureMPC.ErrPooledPrimesRefused) {
+		t.Errorf("Expected using a pool to be refused, got %v", err)
+	}
+	_, _, err := SecureMPC.GeneratePrimesContext(context.Background(), nil, 256, &SecureMPC.SafePrimeOptions{Pool: pool})
+	if !errors.Is(err, SecureMPC.ErrPooledPrimesRefused) {
+		t.Errorf("Expected pooled primes to be refused, got %v", err)
+	}
+	if n, _ := pool.Len(); n != 2 {
+		t.Errorf("Expected refused primes to stay in the pool, %d primes left", n)
+	}
+}
@@ -0,0 +1,103 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestGenerateSafePrime(t *testing.T) {
+	// 6 bits is the smallest size, with 59 = 2*29 + 1 as the only safe prime
+	for _, bits := range []int{6, 9, 64, 256} {
+		p, pprime, err := SecureMPC.GenerateSafePrime(context.Background(), nil, bits, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BitLen() != bits || p.Bit(bits-2) != 1 {
+			t.Errorf("Expected a safe prime of %d bits with the top two bits set, got %v", bits, p)
+		}
+		if !p.ProbablyPrime(20) || !pprime.ProbablyPrime(20) ||
+			new(big.Int).Add(new(big.Int).Lsh(pprime, 1), big.NewInt(1)).Cmp(p) != 0 {
+			t.Errorf("%v is not a safe prime with %v", p, pprime)
+		}
+	}
+	for _, bits := range []int{2, 3, 4, 5} {
+		if _, _, err := SecureMPC.GenerateSafePrime(context.Background(), nil, bits, nil); err == nil {
+			t.Errorf("Expected an error for %d bit safe primes", bits)
+		}
+	}
+}
+
+func TestSafePrimeDoesNotDependOnWorkers(t *testing.T) {
+	var primes [3]*big.Int
+	for i, workers := range []int{1, 2, 8} {
+		p, _, err := SecureMPC.GenerateSafePrime(context.Background(), seededReader(6), 512,
+			&SecureMPC.SafePrimeOptions{Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		primes[i] = p
+	}
+	if primes[0].Cmp(primes[1]) != 0 || primes[0].Cmp(primes[2]) != 0 {
+		t.Errorf("Safe primes from the same seed differ with the number of workers")
+	}
+}
+
+func TestSafePrimeProgressAndCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := []SecureMPC.SafePrimeProgress{}
+	opts := &SecureMPC.SafePrimeOptions{
+		Workers: 4,
+		Progress: func(progress SecureMPC.SafePrimeProgress) {
+			reports = append(reports, progress)
+			if len(reports) == 3 {
+				cancel()
+			}
+		},
+	}
+	_, _, err := SecureMPC.GeneratePrimesContext(ctx, nil, 4096, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the search to be cancelled, got %v", err)
+	}
+	if len(reports) < 3 {
+		t.Fatalf("Expected at least 3 progress reports, got %d", len(reports))
+	}
+	last := reports[len(reports)-1]
+	if last.Bits != 2048 || last.Needed != 2 || last.Found > 1 || last.Candidates < 1<<12 {
+		t.Errorf("Unexpected progress %+v", last)
+	}
+
+	if _, _, err := SecureMPC.GenerateSafePrime(ctx, nil, 256, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled context to be refused, got %v", err)
+	}
+}
+
+func TestGeneratePrimesProgress(t *testing.T) {
+	found := 0
+	opts := &SecureMPC.SafePrimeOptions{
+		Progress: func(progress SecureMPC.SafePrimeProgress) {
+			found = max(found, progress.Found)
+		},
+	}
+	n, m, err := SecureMPC.GeneratePrimesContext(context.Background(), nil, 512, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.BitLen() != 512 || m.BitLen() < 508 {
+		t.Errorf("Unexpected modulus of %d bits", n.BitLen())
+	}
+	if found != 2 {
+		t.Errorf("Expected progress to report 2 safe primes found, got %d", found)
+	}
+}
+
+func BenchmarkGenerateSafePrime1024(b *testing.B) {
+	for b.Loop() {
+		if _, _, err := SecureMPC.GenerateSafePrime(context.Background(), nil, 1024, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package Tests
 
 import (
 	"SecureMPC/SecureMPC"
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -26,3 +27,9 @@ func TestShares(t *testing.T) {
 		t.Errorf("Expected secret 5, got %d", got)
 	}
 }
+
+func TestCreateSharesRefusesMissingBase(t *testing.T) {
+	if err := SecureMPC.MakePlayer(5, 1, 3).CreateShares(SecureMPC.ProtocolData{}); !errors.Is(err, SecureMPC.ErrInvalidParams) {
+		t.Errorf("Expected creating shares without a base to fail, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"SecureMPC/internal/testhooks"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestStoreAndLoadKey(t *testing.T) {
+	message := "Stored once, signed later"
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	passphrase := []byte("correct horse battery staple")
+	if err := SecureMPC.WritePublicParamsFile(filepath.Join(dir, "public.pem"), params); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{1, 3} {
+		path := filepath.Join(dir, "share"+strconv.Itoa(id)+".pem")
+		if err := SecureMPC.WriteKeyShareFile(path, keyShares[id], passphrase, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loadedParams, err := SecureMPC.ReadPublicParamsFile(filepath.Join(dir, "public.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loadedParams.N.Cmp(params.N) != 0 || loadedParams.Delta.Cmp(params.Delta) != 0 ||
+		loadedParams.VerificationKeys[3].Cmp(params.VerificationKeys[3]) != 0 {
+		t.Errorf("Loaded public parameters differ from the stored ones")
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{1, 3} {
+		path := filepath.Join(dir, "share"+strconv.Itoa(id)+".pem")
+		keyShare, err := SecureMPC.ReadKeyShareFile(path, passphrase, loadedParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(loadedParams, keyShare), message)
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Verification failed")
+	}
+}
+
+func TestOpenKeyShareWrongPassphrase(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := SecureMPC.SealKeyShare(keyShares[2], []byte("right"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SecureMPC.OpenKeyShare(sealed, []byte("wrong"), params); err == nil {
+		t.Errorf("Expected opening with the wrong passphrase to fail")
+	}
+	// Changing the id of the key share must make opening fail as well
+	tampered := bytes.Replace(sealed, []byte(`"id":2`), []byte(`"id":1`), 1)
+	if _, err := SecureMPC.OpenKeyShare(tampered, []byte("right"), params); err == nil {
+		t.Errorf("Expected opening a tampered key share to fail")
+	}
+}
+
+func TestOpenKeyShareChecksKey(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherParams, _, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passphrase := []byte("passphrase")
+	sealed, err := SecureMPC.SealKeyShare(keyShares[2], passphrase, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SecureMPC.OpenKeyShare(sealed, passphrase, otherParams); err == nil {
+		t.Errorf("Expected a key share of another key to be refused")
+	}
+	protocol := SecureMPC.NewThresholdProtocolData(params, keyShares)
+	if err := SecureMPC.RefreshShares(protocol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SecureMPC.OpenKeyShare(sealed, passphrase, protocol.PublicParams); !errors.Is(err, SecureMPC.ErrStaleKeyShare) {
+		t.Errorf("Expected a key share of an earlier epoch to be stale, got %v", err)
+	}
+	if _, err := SecureMPC.SealKeyShare(keyShares[2], passphrase, protocol.PublicParams); !errors.Is(err, SecureMPC.ErrStaleKeyShare) {
+		t.Errorf("Expected sealing a key share of an earlier epoch to fail, got %v", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(sealed, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["keyId"] = nil
+	withoutKeyID, _ := json.Marshal(fields)
+	if _, err := SecureMPC.OpenKeyShare(withoutKeyID, passphrase, params); err == nil {
+		t.Errorf("Expected a key share without key id to be refused")
+	}
+}
+
+func TestOpenKeyShareLimitsScrypt(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := SecureMPC.SealKeyShare(keyShares[1], []byte("passphrase"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, change := range map[string]map[string]int{
+		"cost":            {"cost": 1 << 21},
+		"block size":      {"blockSize": 64},
+		"parallelization": {"parallelization": 1 << 20},
+		"memory":          {"cost": 1 << 20, "blockSize": 16},
+	} {
+		var fields map[string]any
+		if err := json.Unmarshal(sealed, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range change {
+			fields[key] = value
+		}
+		changed, _ := json.Marshal(fields)
+		if _, err := SecureMPC.OpenKeyShare(changed, []byte("passphrase"), params); err == nil {
+			t.Errorf("Expected a too large scrypt %s to be refused", name)
+		}
+	}
+}
+
+func TestScryptVectors(t *testing.T) {
+	// The test vectors of RFC 7914, section 12, without the last one, which takes 1 GiB of memory
+	for _, vector := range []struct {
+		passphrase, salt string
+		cost, r, p       int
+		key              string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442" +
+			"fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162" +
+			"2eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+		{"pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2" +
+			"d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+	} {
+		key, err := SecureMPC.Scrypt(testhooks.Key{}, []byte(vector.passphrase), []byte(vector.salt), vector.cost, vector.r, vector.p, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(key) != vector.key {
+			t.Errorf("scrypt of %q with N = %d, r = %d, p = %d gave %x", vector.passphrase, vector.cost, vector.r, vector.p, key)
+		}
+	}
+}
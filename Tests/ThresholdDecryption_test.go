@@ -0,0 +1,133 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestThresholdOAEPDecryption(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeDecryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := &rsa.PublicKey{N: params.N, E: int(params.E.Int64())}
+	plaintext := []byte("Only k players together can read this")
+	label := []byte("label")
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, plaintext, label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares := map[int]*SecureMPC.DecryptionShare{}
+	for _, id := range []int{1, 3} {
+		share, err := SecureMPC.NewThresholdPlayer(params, keyShares[id]).DecryptShare(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !SecureMPC.VerifyDecryptionShare(ciphertext, share, params) {
+			t.Errorf("Decryption share of player %d did not verify", id)
+		}
+		shares[id] = share
+	}
+	if _, err := SecureMPC.CombineDecryptionShares(ciphertext, label, params, shares); !errors.Is(err, SecureMPC.ErrInsufficientShares) {
+		t.Errorf("Expected decryption with k-1 shares to fail")
+	}
+	if shares[4], err = SecureMPC.NewThresholdPlayer(params, keyShares[4]).DecryptShare(ciphertext); err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := SecureMPC.CombineDecryptionShares(ciphertext, label, params, shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected %q, got %q", plaintext, decrypted)
+	}
+	if _, err := SecureMPC.CombineDecryptionShares(ciphertext, []byte("other label"), params, shares); !errors.Is(err, SecureMPC.ErrDecryption) {
+		t.Errorf("Expected decryption with the wrong label to fail")
+	}
+}
+
+func TestDecryptionShareOfOtherCiphertextFails(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeDecryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := &rsa.PublicKey{N: params.N, E: int(params.E.Int64())}
+	first, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, []byte("first"), nil)
+	second, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, []byte("second"), nil)
+	share, err := SecureMPC.NewThresholdPlayer(params, keyShares[2]).DecryptShare(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if SecureMPC.VerifyDecryptionShare(second, share, params) {
+		t.Errorf("Expected decryption share of another ciphertext to fail verification")
+	}
+}
+
+func TestKeyPurposeIsEnforced(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := &rsa.PublicKey{N: params.N, E: int(params.E.Int64())}
+	ciphertext, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, []byte("secret"), nil)
+	if _, err := SecureMPC.NewThresholdPlayer(params, keyShares[1]).DecryptShare(ciphertext); !errors.Is(err, SecureMPC.ErrWrongPurpose) {
+		t.Errorf("Expected a signing key to refuse decryption, got %v", err)
+	}
+	signingKeyID := params.KeyID()
+
+	// A decryption share of the ciphertext would be a signature share of it with a signing key
+	message := "Signed with a decryption key"
+	sigShares := map[int]*SecureMPC.SignatureShare{
+		1: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[1]), message),
+		2: signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), message),
+	}
+	params.Purpose = SecureMPC.PurposeDecryption
+	if bytes.Equal(params.KeyID(), signingKeyID) {
+		t.Errorf("Expected the purpose to change the key id")
+	}
+	if _, err := SecureMPC.NewThresholdPlayer(params, keyShares[1]).SignHashOfMsg(message); !errors.Is(err, SecureMPC.ErrWrongPurpose) {
+		t.Errorf("Expected a decryption key to refuse signing, got %v", err)
+	}
+	if SecureMPC.VerifyShare(message, sigShares[1], params) {
+		t.Errorf("Expected a decryption key to refuse verifying signature shares")
+	}
+	if _, err := SecureMPC.CreateSignature(message, params, sigShares); !errors.Is(err, SecureMPC.ErrWrongPurpose) {
+		t.Errorf("Expected a decryption key to refuse combining signature shares, got %v", err)
+	}
+
+	data, err := params.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &SecureMPC.PublicParams{}
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Purpose != SecureMPC.PurposeDecryption {
+		t.Errorf("Expected the purpose to survive encoding, got %v", decoded.Purpose)
+	}
+}
+
+func TestDealDecryptionKey(t *testing.T) {
+	n, e, d, m := testKey()
+	params, _, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeDecryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing := *params
+	signing.Purpose = SecureMPC.PurposeSigning
+	if params.Purpose != SecureMPC.PurposeDecryption || bytes.Equal(params.KeyID(), signing.KeyID()) {
+		t.Errorf("Expected a key dealt for decryption, got %v", params.Purpose)
+	}
+	if _, _, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.KeyPurpose(7)); !errors.Is(err, SecureMPC.ErrInvalidParams) {
+		t.Errorf("Expected an unknown purpose to be refused, got %v", err)
+	}
+}
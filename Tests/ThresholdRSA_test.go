@@ -2,22 +2,31 @@ package Tests
 
 import (
 	"SecureMPC/SecureMPC"
+	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
 	"testing"
 )
 
 func TestThresholdProtocol(t *testing.T) {
 	message := "Hi hello"
-	data := SecureMPC.ThresholdProtocolSetup(7, 3, 1024)
+	data, err := SecureMPC.ThresholdProtocolSetup(7, 3, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
 	fmt.Println("Data created")
-	SecureMPC.FullSignAndDistribute(message, data)
+	if err := SecureMPC.FullSignAndDistribute(message, data); err != nil {
+		t.Fatal(err)
+	}
 	fmt.Println("Signing completed created")
-	sigmap := data.Participants[1].KnownSignatures[message]
-	sig, valid := SecureMPC.CreateSignature(message, data, sigmap)
-	if !valid {
-		fmt.Println("Error")
+	sigmap := data.Participants[1].KnownShares(message)
+	sig, err := SecureMPC.CreateSignature(message, data.PublicParams, sigmap)
+	if err != nil {
+		fmt.Println("Error:", err)
 	}
-	if SecureMPC.VerifySignature(message, sig, data) {
+	if SecureMPC.VerifySignature(message, sig, data.PublicParams) {
 		fmt.Println("Success!")
 	} else {
 		fmt.Println("Failure!")
@@ -27,3 +36,132 @@ func TestThresholdProtocol(t *testing.T) {
 	// t = k - 1
 
 }
+
+func TestDealToSeparatePlayers(t *testing.T) {
+	message := "Only my own share"
+	params, keyShares, err := SecureMPC.Deal(nil, 5, 3, 1024, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyShares) != 6 || keyShares[0] != nil {
+		t.Fatalf("Expected key shares for players 1..5")
+	}
+	// Every player only gets its own key share and the public parameters
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{5, 2, 4} {
+		player := SecureMPC.NewThresholdPlayer(params, keyShares[id])
+		if player.Id != id {
+			t.Errorf("Expected player %d, got %d", id, player.Id)
+		}
+		share, err := player.SignHashOfMsg(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !SecureMPC.VerifyShare(message, share, params) {
+			t.Errorf("Share of player %d did not verify", id)
+		}
+		sigmap[id] = share
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Verification failed")
+	}
+}
+
+func TestKeySharesHideSecretModuloId(t *testing.T) {
+	// Over the integers s_i = f(0) mod i, so every s_i must be a multiple of i to say nothing about d mod i
+	params, keyShares, err := SecureMPC.Deal(nil, 5, 3, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= params.L; i++ {
+		if new(big.Int).Mod(keyShares[i].Secret(), big.NewInt(int64(i))).Sign() != 0 {
+			t.Errorf("Key share of player %d is not a multiple of %d", i, i)
+		}
+	}
+	if params.Scale.Cmp(params.Delta) != 0 {
+		t.Errorf("Expected Delta as the scale of a dealt key, got %v", params.Scale)
+	}
+}
+
+func TestCreateSignatureCombinesSmallestIds(t *testing.T) {
+	message := "Hi hello"
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 5, 3, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for id := 1; id <= 5; id++ {
+		sigmap[id] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+	}
+	// The share of player 5 is for another message, but only the shares of players 1, 2 and 3 are combined
+	sigmap[5] = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[5]), "Something else")
+	for i := 0; i < 20; i++ {
+		sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+		if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+			t.Fatalf("Verification failed: %v", err)
+		}
+	}
+}
+
+func TestConcurrentSigning(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(5, 3, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Workers = 3
+	// The seeded readers are not safe for concurrent use, the players serialize their reads
+	for id := 1; id <= data.L; id++ {
+		data.Participants[id].Random = seededReader(byte(id))
+	}
+	messages := make([]string, 8)
+	for i := range messages {
+		messages[i] = "Concurrent message " + strconv.Itoa(i)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = SecureMPC.FullSignAndDistribute(message, data)
+		}()
+	}
+	wg.Wait()
+	for i, message := range messages {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		for id := 1; id <= data.L; id++ {
+			shares := data.Participants[id].KnownShares(message)
+			if len(shares) != data.L {
+				t.Fatalf("Player %d knows %d shares of %q", id, len(shares), message)
+			}
+		}
+		sig, err := SecureMPC.CreateSignature(message, data.PublicParams, data.Participants[2].KnownShares(message))
+		if err != nil || !SecureMPC.VerifySignature(message, sig, data.PublicParams) {
+			t.Errorf("Verification of %q failed: %v", message, err)
+		}
+	}
+}
+
+func TestRequestSignaturesReportsFirstFailure(t *testing.T) {
+	n, e, d, m := testKey()
+	data, err := SecureMPC.ThresholdProtocolSetupFromKey(4, 2, n, e, d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := data.Participants[2].KeyShare()
+	if err := SecureMPC.RefreshShares(data); err != nil {
+		t.Fatal(err)
+	}
+	data.Participants[2] = SecureMPC.NewThresholdPlayer(data.PublicParams, stale)
+	data.Participants[4] = SecureMPC.NewThresholdPlayer(data.PublicParams, stale)
+	_, err = SecureMPC.RequestSignatures("Hi hello", data)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrStaleKeyShare) || !errors.As(err, &playerErr) || playerErr.PlayerIds[0] != 2 {
+		t.Errorf("Expected ErrStaleKeyShare of player 2, got %v", err)
+	}
+}
@@ -26,7 +26,8 @@ func TestTimeKeyGen(t *testing.T) {
 		fmt.Println("Generating keys of size: " + strconv.Itoa(keysize))
 		for j := 0; j < runs; j++ {
 			timeS := time.Now()
-			_, _, _, _ = SecureMPC.GenerateRSAKey(keysize)
+			_, _, _, _, err = SecureMPC.GenerateRSAKey(nil, keysize)
+			check(err)
 			fmt.Println("Keygen, run:" + strconv.Itoa(j) + " Time passed: " + time.Since(timeS).String())
 		}
 		avgTimeInSec := time.Since(startTime).Seconds() / float64(runs)
@@ -52,11 +53,13 @@ func TestTimeAll(t *testing.T) {
 	runs := 20
 	for i := 0; i < 4; i++ {
 		keysize := keysizes[i]
-		n, e, d, m := SecureMPC.GenerateRSAKey(keysize)
+		n, e, d, m, keyErr := SecureMPC.GenerateRSAKey(nil, keysize)
+		check(keyErr)
 		for j := 1; j <= 4; j++ {
 			k := j * 16
 			l := k * 2
-			data := SecureMPC.ThresholdProtocolSetupFromKey(l, k, n, e, d, m)
+			data, setupErr := SecureMPC.ThresholdProtocolSetupFromKey(l, k, n, e, d, m)
+			check(setupErr)
 
 			info := "keysize: " + strconv.Itoa(keysize) + ", k: " + strconv.Itoa(k) + ", l: " + strconv.Itoa(l)
 
@@ -66,7 +69,8 @@ func TestTimeAll(t *testing.T) {
 			for a := 0; a < runs; a++ {
 				st1 := time.Now()
 				message := strconv.Itoa(k) + ", " + strconv.Itoa(keysize) + ", " + strconv.Itoa(a)
-				data.Participants[1].SignHashOfMsg(message)
+				_, err = data.Participants[1].SignHashOfMsg(message)
+				check(err)
 				fmt.Println("Signing " + info + " run: " + strconv.Itoa(a) + ", time passed: " + time.Since(st1).String())
 			}
 
@@ -81,16 +85,16 @@ func TestTimeAll(t *testing.T) {
 			// Recombination
 			for a := 0; a < runs; a++ {
 				message := strconv.Itoa(k) + ", " + strconv.Itoa(keysize) + ", " + strconv.Itoa(a)
-				SecureMPC.FullSignAndSendToOne(message, data, 1)
+				check(SecureMPC.FullSignAndSendToOne(message, data, 1))
 			}
 			startTime2 := time.Now()
 			for a := 0; a < runs; a++ {
 				st1 := time.Now()
 				message2 := strconv.Itoa(k) + ", " + strconv.Itoa(keysize) + ", " + strconv.Itoa(a)
-				sigmap := data.Participants[1].KnownSignatures[message2]
-				sig, _ := SecureMPC.CreateSignature(message2, data, sigmap)
+				sigmap := data.Participants[1].KnownShares(message2)
+				sig, _ := SecureMPC.CreateSignature(message2, data.PublicParams, sigmap)
 				fmt.Println("Recomb, " + info + " run: " + strconv.Itoa(a) + ", time passed: " + time.Since(st1).String())
-				if !SecureMPC.VerifySignature(message2, sig, data) {
+				if !SecureMPC.VerifySignature(message2, sig, data.PublicParams) {
 					fmt.Println("FAILURE! " + info)
 				}
 			}
@@ -108,13 +112,14 @@ func TestTimeAll(t *testing.T) {
 			signatureShares := make([]*SecureMPC.SignatureShare, runs)
 			for a := 0; a < runs; a++ {
 				message := strconv.Itoa(k) + ", " + strconv.Itoa(keysize) + ", " + strconv.Itoa(a)
-				signatureShares[a] = data.Participants[1].SignHashOfMsg(message)
+				signatureShares[a], err = data.Participants[1].SignHashOfMsg(message)
+				check(err)
 			}
 			startTime3 := time.Now()
 			for a := 0; a < runs; a++ {
 				st1 := time.Now()
 				message := strconv.Itoa(k) + ", " + strconv.Itoa(keysize) + ", " + strconv.Itoa(a)
-				if !SecureMPC.VerifyShare(message, signatureShares[a], data) {
+				if !SecureMPC.VerifyShare(message, signatureShares[a], data.PublicParams) {
 					fmt.Println("FAILURE! " + info)
 				}
 				fmt.Println("Single verification, " + info + " run: " + strconv.Itoa(a) + ", time passed: " + time.Since(st1).String())
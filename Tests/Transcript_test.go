@@ -0,0 +1,58 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"crypto"
+	"math/big"
+	"testing"
+)
+
+func TestTranscriptChallenge(t *testing.T) {
+	challenge := func(domain string, entries ...string) *big.Int {
+		transcript := SecureMPC.NewTranscript(crypto.SHA256, domain)
+		for i := 0; i+1 < len(entries); i += 2 {
+			transcript.AppendBytes(entries[i], []byte(entries[i+1]))
+		}
+		return transcript.Challenge("c", 256)
+	}
+	first := challenge("proof", "a", "bc")
+	if first.Cmp(challenge("proof", "a", "bc")) != 0 {
+		t.Errorf("Expected the same transcript to give the same challenge")
+	}
+	// Moving bytes between labels and values, or between entries, changes the challenge
+	for _, other := range []*big.Int{challenge("proof", "ab", "c"), challenge("proof", "a", "b", "", "c"), challenge("other proof", "a", "bc")} {
+		if first.Cmp(other) == 0 {
+			t.Errorf("Expected different transcripts to give different challenges")
+		}
+	}
+
+	transcript := SecureMPC.NewTranscript(crypto.SHA3_256, "proof")
+	transcript.AppendBigInt("x", big.NewInt(-5))
+	long := transcript.Challenge("long", 700)
+	short := transcript.Challenge("short", 12)
+	if long.BitLen() > 700 || short.BitLen() > 12 {
+		t.Errorf("Challenges are longer than requested")
+	}
+}
+
+func TestProofWithOtherHash(t *testing.T) {
+	n, e, d, m := testKey()
+	params, keyShares, err := SecureMPC.DealFromKey(nil, 3, 2, n, e, d, m, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Hi hello"
+	share := signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), message)
+	params.Proof.Hash = crypto.SHA384
+	if SecureMPC.VerifyShare(message, share, params) {
+		t.Errorf("Expected a proof made with SHA-256 to fail with SHA-384")
+	}
+	share = signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), message)
+	if !SecureMPC.VerifyShare(message, share, params) {
+		t.Errorf("Share proven with SHA-384 did not verify")
+	}
+	params.Proof.Hash = crypto.MD5
+	if _, err := SecureMPC.NewThresholdPlayer(params, keyShares[2]).SignHashOfMsg(message); err == nil {
+		t.Errorf("Expected proofs with MD5 to be refused")
+	}
+}
@@ -0,0 +1,105 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestValidatePublicParams(t *testing.T) {
+	params, _, err := SecureMPC.Deal(nil, 5, 3, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Validate(); err != nil {
+		t.Fatalf("Expected dealt parameters to be valid, got %v", err)
+	}
+	nonSquare := big.NewInt(2)
+	for big.Jacobi(nonSquare, params.N) != -1 {
+		nonSquare.Add(nonSquare, big.NewInt(1))
+	}
+
+	tests := []struct {
+		name   string
+		change func(p *SecureMPC.PublicParams)
+	}{
+		{"k of 0", func(p *SecureMPC.PublicParams) { p.K = 0 }},
+		{"k above l", func(p *SecureMPC.PublicParams) { p.K = 6 }},
+		{"even N", func(p *SecureMPC.PublicParams) { p.N = new(big.Int).Add(p.N, big.NewInt(1)) }},
+		{"e not above l", func(p *SecureMPC.PublicParams) { p.E = big.NewInt(5) }},
+		{"composite e", func(p *SecureMPC.PublicParams) { p.E = big.NewInt(65535) }},
+		{"wrong Delta", func(p *SecureMPC.PublicParams) { p.Delta = big.NewInt(24) }},
+		{"e dividing the scale", func(p *SecureMPC.PublicParams) { p.Scale = new(big.Int).Set(p.E) }},
+		{"V not a square", func(p *SecureMPC.PublicParams) { p.V = nonSquare }},
+		{"V sharing a factor with N", func(p *SecureMPC.PublicParams) { p.V = new(big.Int).Set(p.N) }},
+		{"missing verification key", func(p *SecureMPC.PublicParams) { p.VerificationKeys = p.VerificationKeys[:5] }},
+		{"unknown encoding", func(p *SecureMPC.PublicParams) { p.Encoding = 42 }},
+		{"negative epoch", func(p *SecureMPC.PublicParams) { p.Epoch = -1 }},
+	}
+	for _, test := range tests {
+		changed := *params
+		changed.VerificationKeys = append([]*big.Int{}, params.VerificationKeys...)
+		test.change(&changed)
+		if err := changed.Validate(); !errors.Is(err, SecureMPC.ErrInvalidParams) {
+			t.Errorf("%s: expected ErrInvalidParams, got %v", test.name, err)
+		}
+	}
+
+	changed := *params
+	changed.VerificationKeys = append([]*big.Int{}, params.VerificationKeys...)
+	changed.VerificationKeys[4] = nonSquare
+	err = changed.Validate()
+	var playerErr *SecureMPC.PlayerError
+	if !errors.As(err, &playerErr) || len(playerErr.PlayerIds) != 1 || playerErr.PlayerIds[0] != 4 {
+		t.Errorf("Expected the invalid verification key of player 4 to be named, got %v", err)
+	}
+}
+
+func TestSetupFromKeyRefusesInvalidThresholds(t *testing.T) {
+	n, e, d, m, err := SecureMPC.GenerateRSAKey(nil, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lk := range [][2]int{{3, 4}, {3, 0}, {0, 0}} {
+		if _, err := SecureMPC.ThresholdProtocolSetupFromKey(lk[0], lk[1], n, e, d, m); !errors.Is(err, SecureMPC.ErrInvalidParams) {
+			t.Errorf("Expected %d of %d players to be refused, got %v", lk[1], lk[0], err)
+		}
+	}
+	// With e = 3 there must be fewer than 3 players
+	three := big.NewInt(3)
+	d3 := new(big.Int).ModInverse(three, m)
+	if d3 == nil {
+		t.Skip("3 is not invertible modulo m")
+	}
+	if _, err := SecureMPC.ThresholdProtocolSetupFromKey(3, 2, n, three, d3, m); !errors.Is(err, SecureMPC.ErrInvalidParams) {
+		t.Errorf("Expected e = l to be refused, got %v", err)
+	}
+	if _, err := SecureMPC.ThresholdProtocolSetupFromKey(2, 2, n, three, d3, m); err != nil {
+		t.Errorf("Expected e > l to be accepted, got %v", err)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 5, 3, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Encoding = SecureMPC.EncodingPSS
+	if err := SecureMPC.SelfTest(nil, params, keyShares, 5); err != nil {
+		t.Fatalf("Expected the self test to pass, got %v", err)
+	}
+
+	tampered := append([]*SecureMPC.KeyShare{}, keyShares...)
+	tampered[2] = SecureMPC.NewKeyShare(2, 0, new(big.Int).Add(keyShares[2].Secret(), big.NewInt(1)))
+	err = SecureMPC.SelfTest(nil, params, tampered, 5)
+	var playerErr *SecureMPC.PlayerError
+	if !errors.Is(err, SecureMPC.ErrSelfTest) || !errors.As(err, &playerErr) ||
+		len(playerErr.PlayerIds) != 1 || playerErr.PlayerIds[0] != 2 {
+		t.Errorf("Expected the self test to name the tampered key share of player 2, got %v", err)
+	}
+
+	if err := SecureMPC.SelfTest(nil, params, keyShares[:4], 1); !errors.Is(err, SecureMPC.ErrSelfTest) {
+		t.Errorf("Expected missing key shares to fail the self test, got %v", err)
+	}
+}
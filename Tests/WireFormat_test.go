@@ -0,0 +1,143 @@
+package Tests
+
+import (
+	"SecureMPC/SecureMPC"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSignatureShareWireFormat(t *testing.T) {
+	message := "Sent between processes"
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigmap := map[int]*SecureMPC.SignatureShare{}
+	for _, id := range []int{1, 3} {
+		share := signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[id]), message)
+		encoded, err := share.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := SecureMPC.DecodeSignatureShare(encoded, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Id() != id || !bytes.Equal(decoded.KeyID(), params.KeyID()) {
+			t.Errorf("Decoded share has wrong id or key id")
+		}
+		reencoded, _ := decoded.MarshalBinary()
+		if !bytes.Equal(encoded, reencoded) {
+			t.Errorf("Encoding is not stable")
+		}
+		if !SecureMPC.VerifyShare(message, decoded, params) {
+			t.Errorf("Decoded share of player %d did not verify", id)
+		}
+		sigmap[id] = decoded
+	}
+	sig, err := SecureMPC.CreateSignature(message, params, sigmap)
+	if err != nil || !SecureMPC.VerifySignature(message, sig, params) {
+		t.Errorf("Verification failed")
+	}
+
+	jsonShare, err := json.Marshal(sigmap[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := &SecureMPC.SignatureShare{}
+	if err := json.Unmarshal(jsonShare, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromJSON.CheckRange(params); err != nil || !SecureMPC.VerifyShare(message, fromJSON, params) {
+		t.Errorf("Share decoded from JSON did not verify")
+	}
+}
+
+func TestDecodeSignatureShareRejects(t *testing.T) {
+	params, keyShares, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherParams, _, err := SecureMPC.Deal(nil, 3, 2, 512, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), "message")
+	encoded, _ := share.MarshalBinary()
+	if _, err := SecureMPC.DecodeSignatureShare(encoded, otherParams); err == nil {
+		t.Errorf("Expected share of another key to be rejected")
+	}
+	if _, err := SecureMPC.DecodeSignatureShare(append(encoded, 0), params); err == nil {
+		t.Errorf("Expected trailing data to be rejected")
+	}
+	if _, err := SecureMPC.DecodeSignatureShare(encoded[:len(encoded)-1], params); err == nil {
+		t.Errorf("Expected truncated share to be rejected")
+	}
+	// Player id 4 is out of range for l = 3
+	digestLength := int(encoded[1+SecureMPC.KeyIDSize])<<8 | int(encoded[2+SecureMPC.KeyIDSize])
+	idOffset := 3 + SecureMPC.KeyIDSize + digestLength
+	wrongId := append([]byte(nil), encoded...)
+	wrongId[idOffset+3] = 4
+	if _, err := SecureMPC.DecodeSignatureShare(wrongId, params); err == nil {
+		t.Errorf("Expected out of range player id to be rejected")
+	}
+	if SecureMPC.VerifyShare("another message", share, params) {
+		t.Errorf("Expected share of another message to fail verification")
+	}
+}
+
+func TestUncheckedSharesAreRejected(t *testing.T) {
+	key := safePrimeRSAKey(t, 256, 65537)
+	params, keyShares, err := SecureMPC.DealFromRSAKey(nil, 3, 2, key, SecureMPC.PurposeSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "Decoded without a range check"
+	share := signShare(t, SecureMPC.NewThresholdPlayer(params, keyShares[2]), message)
+	jsonShare, err := json.Marshal(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A signature of 0 and a multiple of a factor of N have no inverse modulo N
+	for name, signature := range map[string]string{
+		"zero":                  "",
+		"sharing a factor of N": hex.EncodeToString(key.Primes[0].Bytes()),
+	} {
+		var fields map[string]any
+		if err := json.Unmarshal(jsonShare, &fields); err != nil {
+			t.Fatal(err)
+		}
+		fields["signature"] = signature
+		changed, _ := json.Marshal(fields)
+		decoded := &SecureMPC.SignatureShare{}
+		if err := json.Unmarshal(changed, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.CheckRange(params) == nil {
+			t.Errorf("Expected a signature %s to be out of range", name)
+		}
+		if SecureMPC.VerifyShare(message, decoded, params) {
+			t.Errorf("Expected a signature %s to fail verification", name)
+		}
+		player := SecureMPC.NewThresholdPlayer(params, keyShares[1])
+		if err := player.AddShare(message, decoded); !errors.Is(err, SecureMPC.ErrInvalidShare) {
+			t.Errorf("Expected a signature %s to be refused, got %v", name, err)
+		}
+		sigmap := map[int]*SecureMPC.SignatureShare{
+			1: signShare(t, player, message),
+			2: decoded,
+		}
+		if _, err := SecureMPC.CreateSignature(message, params, sigmap); !errors.Is(err, SecureMPC.ErrInvalidShare) {
+			t.Errorf("Expected combining a signature %s to fail, got %v", name, err)
+		}
+	}
+	if SecureMPC.VerifyShare(message, nil, params) {
+		t.Errorf("Expected a nil share to fail verification")
+	}
+	if err := SecureMPC.NewThresholdPlayer(params, keyShares[1]).AddShare(message, nil); !errors.Is(err, SecureMPC.ErrInvalidShare) {
+		t.Errorf("Expected a nil share to be refused, got %v", err)
+	}
+}
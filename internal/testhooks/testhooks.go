@@ -0,0 +1,6 @@
+// Package testhooks holds the key of the test hooks of SecureMPC. Only packages of this module can name Key, so
+// the hooks taking it can be called by the tests in Tests but are not part of the API.
+package testhooks
+
+// Key must be passed to the test hooks of SecureMPC
+type Key struct{}